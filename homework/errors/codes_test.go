@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v homework_test.go codes_test.go
+
+// Code values are the same as gRPC canonical codes,
+// so they can be converted to codes.Code directly.
+type Code uint32
+
+const (
+	CodeOK Code = iota
+	CodeCanceled
+	CodeUnknown
+	CodeInvalidArgument
+	CodeDeadlineExceeded
+	CodeNotFound
+	CodeAlreadyExists
+	CodePermissionDenied
+	CodeResourceExhausted
+	CodeFailedPrecondition
+	CodeAborted
+	CodeOutOfRange
+	CodeUnimplemented
+	CodeInternal
+	CodeUnavailable
+	CodeDataLoss
+	CodeUnauthenticated
+)
+
+var codeNames = map[Code]string{
+	CodeOK:                 "OK",
+	CodeCanceled:           "CANCELED",
+	CodeUnknown:            "UNKNOWN",
+	CodeInvalidArgument:    "INVALID_ARGUMENT",
+	CodeDeadlineExceeded:   "DEADLINE_EXCEEDED",
+	CodeNotFound:           "NOT_FOUND",
+	CodeAlreadyExists:      "ALREADY_EXISTS",
+	CodePermissionDenied:   "PERMISSION_DENIED",
+	CodeResourceExhausted:  "RESOURCE_EXHAUSTED",
+	CodeFailedPrecondition: "FAILED_PRECONDITION",
+	CodeAborted:            "ABORTED",
+	CodeOutOfRange:         "OUT_OF_RANGE",
+	CodeUnimplemented:      "UNIMPLEMENTED",
+	CodeInternal:           "INTERNAL",
+	CodeUnavailable:        "UNAVAILABLE",
+	CodeDataLoss:           "DATA_LOSS",
+	CodeUnauthenticated:    "UNAUTHENTICATED",
+}
+
+var httpStatuses = map[Code]int{
+	CodeOK:                 http.StatusOK,
+	CodeCanceled:           499, // client closed request
+	CodeUnknown:            http.StatusInternalServerError,
+	CodeInvalidArgument:    http.StatusBadRequest,
+	CodeDeadlineExceeded:   http.StatusGatewayTimeout,
+	CodeNotFound:           http.StatusNotFound,
+	CodeAlreadyExists:      http.StatusConflict,
+	CodePermissionDenied:   http.StatusForbidden,
+	CodeResourceExhausted:  http.StatusTooManyRequests,
+	CodeFailedPrecondition: http.StatusBadRequest,
+	CodeAborted:            http.StatusConflict,
+	CodeOutOfRange:         http.StatusBadRequest,
+	CodeUnimplemented:      http.StatusNotImplemented,
+	CodeInternal:           http.StatusInternalServerError,
+	CodeUnavailable:        http.StatusServiceUnavailable,
+	CodeDataLoss:           http.StatusInternalServerError,
+	CodeUnauthenticated:    http.StatusUnauthorized,
+}
+
+var httpCodes = map[int]Code{
+	http.StatusOK:                  CodeOK,
+	http.StatusBadRequest:          CodeInvalidArgument,
+	http.StatusUnauthorized:        CodeUnauthenticated,
+	http.StatusForbidden:           CodePermissionDenied,
+	http.StatusNotFound:            CodeNotFound,
+	http.StatusConflict:            CodeAlreadyExists,
+	http.StatusTooManyRequests:     CodeResourceExhausted,
+	499:                            CodeCanceled,
+	http.StatusInternalServerError: CodeInternal,
+	http.StatusNotImplemented:      CodeUnimplemented,
+	http.StatusServiceUnavailable:  CodeUnavailable,
+	http.StatusGatewayTimeout:      CodeDeadlineExceeded,
+}
+
+var retryableCodes = map[Code]bool{
+	CodeDeadlineExceeded:  true,
+	CodeResourceExhausted: true,
+	CodeAborted:           true,
+	CodeUnavailable:       true,
+}
+
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("CODE(%d)", uint32(c))
+}
+
+func (c Code) HTTPStatus() int {
+	if status, ok := httpStatuses[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+func CodeFromHTTPStatus(status int) Code {
+	if code, ok := httpCodes[status]; ok {
+		return code
+	}
+
+	switch {
+	case status >= 200 && status < 300:
+		return CodeOK
+	case status >= 400 && status < 500:
+		return CodeFailedPrecondition
+	default:
+		return CodeUnknown
+	}
+}
+
+type CodedError struct {
+	code    Code
+	message string
+	err     error
+}
+
+func New(code Code, message string) error {
+	return &CodedError{code: code, message: message}
+}
+
+func Wrap(err error, code Code) error {
+	if err == nil {
+		return nil
+	}
+	return &CodedError{code: code, err: err}
+}
+
+func (e *CodedError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return e.message
+}
+
+func (e *CodedError) Code() Code {
+	return e.code
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.err
+}
+
+// CodeOf returns the first code found in the tree of errors,
+// errors without code are treated as unknown ones
+func CodeOf(err error) Code {
+	if err == nil {
+		return CodeOK
+	}
+
+	var coder interface{ Code() Code }
+	switch {
+	case errors.As(err, &coder):
+		return coder.Code()
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeDeadlineExceeded
+	default:
+		return CodeUnknown
+	}
+}
+
+// Retryable reports whether retry can help: the nearest code in the chain
+// must be retryable, and for many errors all of them must be retryable
+func Retryable(err error) bool {
+	switch e := err.(type) {
+	case nil:
+		return false
+	case interface{ Retryable() bool }:
+		return e.Retryable()
+	case interface{ Code() Code }:
+		return retryableCodes[e.Code()]
+	case interface{ Unwrap() []error }:
+		var found bool
+		for _, child := range e.Unwrap() {
+			if child == nil {
+				continue
+			}
+			if !Retryable(child) {
+				return false
+			}
+			found = true
+		}
+		return found
+	case interface{ Unwrap() error }:
+		return Retryable(e.Unwrap())
+	default:
+		return retryableCodes[CodeOf(err)]
+	}
+}
+
+type errorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// WriteHTTPError renders error as JSON, details of internal errors are hidden,
+// nil error is rendered as OK with empty message
+func WriteHTTPError(w http.ResponseWriter, err error) {
+	code := CodeOf(err)
+	response := errorResponse{Code: code.String()}
+	if err != nil {
+		response.Message = err.Error()
+	}
+	if code == CodeUnknown || code == CodeInternal || code == CodeDataLoss {
+		response.Message = "internal error"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code.HTTPStatus())
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func TestCodeOf(t *testing.T) {
+	errNotFound := New(CodeNotFound, "user not found")
+
+	tests := map[string]struct {
+		err  error
+		code Code
+	}{
+		"nil error":        {err: nil, code: CodeOK},
+		"plain error":      {err: errors.New("error"), code: CodeUnknown},
+		"coded error":      {err: errNotFound, code: CodeNotFound},
+		"wrapped error":    {err: fmt.Errorf("get user: %w", errNotFound), code: CodeNotFound},
+		"recoded error":    {err: Wrap(fmt.Errorf("get user: %w", errNotFound), CodeInternal), code: CodeInternal},
+		"multi error":      {err: Append(errors.New("error"), fmt.Errorf("get user: %w", errNotFound)), code: CodeNotFound},
+		"wrapped multi":    {err: fmt.Errorf("batch: %w", Append(errors.New("error"), errNotFound)), code: CodeNotFound},
+		"joined errors":    {err: errors.Join(errors.New("error"), errNotFound), code: CodeNotFound},
+		"canceled context": {err: fmt.Errorf("query: %w", context.Canceled), code: CodeCanceled},
+		"deadline":         {err: context.DeadlineExceeded, code: CodeDeadlineExceeded},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.code, CodeOf(test.err))
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	errUnavailable := New(CodeUnavailable, "database is unavailable")
+	errInvalid := New(CodeInvalidArgument, "invalid name")
+
+	tests := map[string]struct {
+		err       error
+		retryable bool
+	}{
+		"nil error":            {err: nil},
+		"plain error":          {err: errors.New("error")},
+		"unavailable":          {err: errUnavailable, retryable: true},
+		"wrapped unavailable":  {err: fmt.Errorf("save: %w", errUnavailable), retryable: true},
+		"invalid argument":     {err: errInvalid},
+		"deadline":             {err: fmt.Errorf("query: %w", context.DeadlineExceeded), retryable: true},
+		"all retryable":        {err: Append(errUnavailable, New(CodeAborted, "conflict")), retryable: true},
+		"partially retryable":  {err: Append(errUnavailable, errInvalid)},
+		"recoded non-retrying": {err: Wrap(errUnavailable, CodeInternal)},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.retryable, Retryable(test.err))
+		})
+	}
+}
+
+func TestHTTPMapping(t *testing.T) {
+	for code := range codeNames {
+		status := code.HTTPStatus()
+		assert.NotZero(t, status, code.String())
+		assert.Equal(t, status, CodeFromHTTPStatus(status).HTTPStatus(), code.String())
+	}
+
+	assert.Equal(t, CodeNotFound, CodeFromHTTPStatus(http.StatusNotFound))
+	assert.Equal(t, CodeFailedPrecondition, CodeFromHTTPStatus(http.StatusTeapot))
+	assert.Equal(t, CodeUnknown, CodeFromHTTPStatus(http.StatusBadGateway))
+	assert.Equal(t, "CODE(100)", Code(100).String())
+	assert.Equal(t, http.StatusInternalServerError, Code(100).HTTPStatus())
+}
+
+func TestWriteHTTPError(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		status   int
+		response errorResponse
+	}{
+		"not found": {
+			err:      fmt.Errorf("get user: %w", New(CodeNotFound, "user not found")),
+			status:   http.StatusNotFound,
+			response: errorResponse{Code: "NOT_FOUND", Message: "get user: user not found"},
+		},
+		"internal": {
+			err:      errors.New("connection refused to 10.0.0.1"),
+			status:   http.StatusInternalServerError,
+			response: errorResponse{Code: "UNKNOWN", Message: "internal error"},
+		},
+		"nil error": {
+			status:   http.StatusOK,
+			response: errorResponse{Code: "OK"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			WriteHTTPError(recorder, test.err)
+			assert.Equal(t, test.status, recorder.Code)
+			assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+			var response errorResponse
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+			assert.Equal(t, test.response, response)
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v -race homework_test.go collector_test.go
+
+// Collector accumulates errors from many goroutines, the same error
+// is stored once, errors beyond the limit are only counted.
+// Zero value is ready to use and has no limit.
+type Collector struct {
+	mutex   sync.Mutex
+	errs    []error
+	seen    map[error]struct{} // comparable errors, others are compared with errors.Is
+	limit   int
+	dropped int
+}
+
+// NewCollector creates collector keeping at most limit errors, zero limit means no limit
+func NewCollector(limit int) *Collector {
+	return &Collector{limit: limit}
+}
+
+func (c *Collector) Append(errs ...error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if m, ok := err.(*MultiError); ok {
+			for _, v := range m.errs {
+				c.add(v)
+			}
+		} else {
+			c.add(err)
+		}
+	}
+}
+
+// add deduplicates errors by identity, so different sentinel errors
+// with the same message are kept, after the limit is reached errors
+// are only counted, so memory doesn't grow
+func (c *Collector) add(err error) {
+	found, hashable := c.lookup(err)
+	if found {
+		return
+	}
+
+	if c.dropped > 0 || c.limit > 0 && len(c.errs) >= c.limit {
+		c.dropped++
+		return
+	}
+
+	if hashable {
+		if c.seen == nil {
+			c.seen = make(map[error]struct{})
+		}
+		c.seen[err] = struct{}{}
+	}
+	c.errs = append(c.errs, err)
+}
+
+// lookup reports whether err is already stored and whether it can be a map key,
+// a comparable struct may still hold not comparable error in its field,
+// so hashing panics and such errors are compared with errors.Is
+func (c *Collector) lookup(err error) (found, hashable bool) {
+	defer func() {
+		if recover() != nil {
+			found = slices.ContainsFunc(c.errs, func(seen error) bool { return safeIs(seen, err) })
+			hashable = false
+		}
+	}()
+
+	_, found = c.seen[err]
+	return found, true
+}
+
+// safeIs is errors.Is which treats errors as different when comparison panics
+func safeIs(err, target error) (is bool) {
+	defer func() {
+		if recover() != nil {
+			is = false
+		}
+	}()
+	return errors.Is(err, target)
+}
+
+func (c *Collector) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return len(c.errs) + c.dropped
+}
+
+// ErrorOrNil returns *MultiError with a copy of collected errors or untyped nil
+func (c *Collector) ErrorOrNil() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(c.errs) == 0 {
+		return nil
+	}
+
+	errs := make([]error, len(c.errs), len(c.errs)+1)
+	copy(errs, c.errs)
+	if c.dropped > 0 {
+		errs = append(errs, &TruncatedError{Count: c.dropped})
+	}
+	return &MultiError{errs: errs}
+}
+
+type TruncatedError struct {
+	Count int
+}
+
+func (e *TruncatedError) Error() string {
+	if e.Count == 1 {
+		return "and 1 more error"
+	}
+	return fmt.Sprintf("and %d more errors", e.Count)
+}
+
+func TestCollectorConcurrentAppend(t *testing.T) {
+	collector := NewCollector(0)
+
+	wg := sync.WaitGroup{}
+	wg.Add(100)
+	for i := range 100 {
+		go func() {
+			defer wg.Done()
+			collector.Append(fmt.Errorf("worker %d failed", i))
+		}()
+	}
+	wg.Wait()
+
+	err := collector.ErrorOrNil()
+	require.Error(t, err)
+
+	var merr *MultiError
+	require.ErrorAs(t, err, &merr)
+	assert.Len(t, merr.errs, 100)
+	assert.Equal(t, 100, collector.Len())
+}
+
+func TestCollectorLimit(t *testing.T) {
+	collector := NewCollector(2)
+	for i := range 5 {
+		collector.Append(fmt.Errorf("error %d", i))
+	}
+
+	err := collector.ErrorOrNil()
+	assert.EqualError(t, err, "3 errors occurred:\n\t* error 0\n\t* error 1\n\t* and 3 more errors\n")
+	assert.Equal(t, 5, collector.Len())
+
+	var truncated *TruncatedError
+	require.ErrorAs(t, err, &truncated)
+	assert.Equal(t, 3, truncated.Count)
+	assert.Len(t, collector.seen, 2)
+
+	collector = NewCollector(1)
+	collector.Append(errors.New("error 0"), errors.New("error 1"))
+	assert.EqualError(t, collector.ErrorOrNil(), "2 errors occurred:\n\t* error 0\n\t* and 1 more error\n")
+}
+
+func TestCollectorDeduplication(t *testing.T) {
+	errTimeout := errors.New("timeout")
+
+	collector := NewCollector(0)
+	collector.Append(errTimeout, errTimeout, nil)
+	// other sentinel error with the same message isn't a duplicate
+	collector.Append(errors.New("timeout"))
+	collector.Append(Append(errors.New("error 1"), errTimeout))
+
+	// errors of not comparable types are compared with errors.Is
+	errInvalid := invalidFieldsError{"name", "age"}
+	collector.Append(errInvalid, errInvalid, invalidFieldsError{"email"})
+
+	err := collector.ErrorOrNil()
+	assert.EqualError(t, err, "5 errors occurred:\n\t* timeout\n\t* timeout\n\t* error 1\n"+
+		"\t* invalid fields: [name age]\n\t* invalid fields: [email]\n")
+	assert.ErrorIs(t, err, errTimeout)
+}
+
+type invalidFieldsError []string
+
+func (e invalidFieldsError) Error() string {
+	return fmt.Sprintf("invalid fields: %v", []string(e))
+}
+
+func (e invalidFieldsError) Is(target error) bool {
+	other, ok := target.(invalidFieldsError)
+	return ok && slices.Equal(e, other)
+}
+
+type opError struct {
+	Op  string
+	Err error
+}
+
+func (e opError) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e opError) Unwrap() error {
+	return e.Err
+}
+
+func TestCollectorNotHashableErrors(t *testing.T) {
+	errInvalid := invalidFieldsError{"a"}
+	errSave := opError{Op: "save", Err: errInvalid}
+
+	collector := NewCollector(0)
+	require.NotPanics(t, func() {
+		collector.Append(errSave, errSave)
+	})
+
+	// the wrapper is comparable, but its field isn't,
+	// so such errors can't be compared and both are kept
+	assert.Equal(t, 2, collector.Len())
+	assert.ErrorIs(t, collector.ErrorOrNil(), errInvalid)
+}
+
+func TestCollectorDuplicatesAfterLimit(t *testing.T) {
+	errTimeout := errors.New("timeout")
+	errInvalid := invalidFieldsError{"name"}
+
+	collector := NewCollector(2)
+	collector.Append(errTimeout, errInvalid, errors.New("error"))
+	collector.Append(errTimeout, errInvalid)
+
+	assert.Equal(t, 3, collector.Len())
+	assert.EqualError(t, collector.ErrorOrNil(), "3 errors occurred:\n"+
+		"\t* timeout\n\t* invalid fields: [name]\n\t* and 1 more error\n")
+}
+
+func TestCollectorZeroValue(t *testing.T) {
+	var collector Collector
+	collector.Append(errors.New("error 0"), errors.New("error 1"))
+	assert.Equal(t, 2, collector.Len())
+	assert.Error(t, collector.ErrorOrNil())
+}
+
+func TestCollectorWithoutErrors(t *testing.T) {
+	collector := NewCollector(10)
+	collector.Append(nil, nil)
+
+	err := collector.ErrorOrNil()
+	assert.True(t, err == nil)
+	assert.Zero(t, collector.Len())
+}
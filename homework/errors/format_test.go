@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v homework_test.go format_test.go
+
+type ErrorFormatFunc func(errs []error) string
+
+// ListFormat is the format of MultiError.Error
+func ListFormat(errs []error) string {
+	return (&MultiError{errs: errs}).Error()
+}
+
+// TreeFormat prints every error on its own line, nested and wrapped errors
+// are placed under their parents with additional indentation.
+func TreeFormat(errs []error) string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%d errors occurred:\n", len(errs)))
+	for _, err := range errs {
+		writeTree(&sb, err, 1)
+	}
+	return sb.String()
+}
+
+func writeTree(sb *strings.Builder, err error, depth int) {
+	sb.WriteString(strings.Repeat("  ", depth-1))
+	sb.WriteString("* ")
+
+	switch wrapper := err.(type) {
+	case interface{ Unwrap() []error }:
+		children := wrapper.Unwrap()
+		sb.WriteString(fmt.Sprintf("%d errors occurred:\n", len(children)))
+		for _, child := range children {
+			writeTree(sb, child, depth+1)
+		}
+
+	case interface{ Unwrap() error }:
+		sb.WriteString(indentLines(err.Error(), strings.Repeat("  ", depth)))
+		sb.WriteByte('\n')
+		if cause := wrapper.Unwrap(); cause != nil {
+			writeTree(sb, cause, depth+1)
+		}
+
+	default:
+		sb.WriteString(indentLines(err.Error(), strings.Repeat("  ", depth)))
+		sb.WriteByte('\n')
+	}
+}
+
+// SingleLineFormat joins errors with semicolons, nested errors are put into brackets
+func SingleLineFormat(errs []error) string {
+	parts := make([]string, 0, len(errs))
+	for _, err := range errs {
+		if nested, ok := err.(interface{ Unwrap() []error }); ok {
+			parts = append(parts, "["+SingleLineFormat(nested.Unwrap())+"]")
+		} else {
+			parts = append(parts, strings.ReplaceAll(err.Error(), "\n", " "))
+		}
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(errs), strings.Join(parts, "; "))
+}
+
+// JSONFormat describes every error with its type, message and unwrapped errors
+func JSONFormat(errs []error) string {
+	data, _ := json.Marshal(describeError(&MultiError{errs: errs}))
+	return string(data)
+}
+
+type errorDescription struct {
+	Type    string             `json:"type"`
+	Message string             `json:"message"`
+	Wrapped *errorDescription  `json:"wrapped,omitempty"`
+	Errors  []errorDescription `json:"errors,omitempty"`
+}
+
+func describeError(err error) errorDescription {
+	description := errorDescription{
+		Type:    reflect.TypeOf(err).String(),
+		Message: err.Error(),
+	}
+
+	switch wrapper := err.(type) {
+	case interface{ Unwrap() []error }:
+		description.Errors = make([]errorDescription, 0, len(wrapper.Unwrap()))
+		for _, child := range wrapper.Unwrap() {
+			if child != nil {
+				description.Errors = append(description.Errors, describeError(child))
+			}
+		}
+
+	case interface{ Unwrap() error }:
+		if cause := wrapper.Unwrap(); cause != nil {
+			wrapped := describeError(cause)
+			description.Wrapped = &wrapped
+		}
+	}
+
+	return description
+}
+
+func (e *MultiError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(describeError(e))
+}
+
+// WithFormat returns the same errors with other message format,
+// errors.Is and errors.As still see the original MultiError.
+func (e *MultiError) WithFormat(format ErrorFormatFunc) error {
+	return &formattedError{merr: e, format: format}
+}
+
+// formattedError doesn't embed MultiError, otherwise its Format
+// would be promoted and print errors in the default format
+type formattedError struct {
+	merr   *MultiError
+	format ErrorFormatFunc
+}
+
+func (e *formattedError) Error() string {
+	return e.format(e.merr.errs)
+}
+
+func (e *formattedError) Unwrap() error {
+	return e.merr
+}
+
+// Format uses the chosen format for all verbs, stack traces
+// are printed only by %+v of the original MultiError
+func (e *formattedError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v', 's':
+		_, _ = io.WriteString(s, e.Error())
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", e.Error())
+	default:
+		_, _ = fmt.Fprintf(s, "%%!%c(*main.formattedError=%s)", verb, e.Error())
+	}
+}
+
+// Format prints every error with %+v verb, so errors with stack traces
+// from github.com/pkg/errors print their stacks too
+func (e *MultiError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			_, _ = fmt.Fprintf(s, "%d errors occurred:\n", len(e.errs))
+			for _, err := range e.errs {
+				_, _ = io.WriteString(s, "\t* ")
+				_, _ = io.WriteString(s, indentLines(fmt.Sprintf("%+v", err), "\t  "))
+				_, _ = io.WriteString(s, "\n")
+			}
+			return
+		}
+		fallthrough
+	case 's':
+		_, _ = io.WriteString(s, e.Error())
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", e.Error())
+	default:
+		_, _ = fmt.Fprintf(s, "%%!%c(*main.MultiError=%s)", verb, e.Error())
+	}
+}
+
+func indentLines(text, prefix string) string {
+	return strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
+
+func TestMultiErrorFormats(t *testing.T) {
+	errNotFound := errors.New("file not found")
+	nested := Append(errors.New("name is empty"), errors.New("age is negative"))
+	merr := Append(
+		fmt.Errorf("load config: %w", fmt.Errorf("open config.yaml: %w", errNotFound)),
+		nested,
+		errors.New("timeout"),
+	)
+
+	tests := map[string]struct {
+		format ErrorFormatFunc
+		result string
+	}{
+		"list": {
+			format: ListFormat,
+			result: "4 errors occurred:\n" +
+				"\t* load config: open config.yaml: file not found\n" +
+				"\t* name is empty\n" +
+				"\t* age is negative\n" +
+				"\t* timeout\n",
+		},
+		"tree": {
+			format: TreeFormat,
+			result: "4 errors occurred:\n" +
+				"* load config: open config.yaml: file not found\n" +
+				"  * open config.yaml: file not found\n" +
+				"    * file not found\n" +
+				"* name is empty\n" +
+				"* age is negative\n" +
+				"* timeout\n",
+		},
+		"single line": {
+			format: SingleLineFormat,
+			result: "4 errors occurred: load config: open config.yaml: file not found; " +
+				"name is empty; age is negative; timeout",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := merr.WithFormat(test.format)
+			assert.EqualError(t, err, test.result)
+			assert.Equal(t, test.result, fmt.Sprint(err))
+			assert.Equal(t, test.result, fmt.Sprintf("%v", err))
+			assert.Equal(t, test.result, fmt.Sprintf("%+v", err))
+			assert.ErrorIs(t, err, errNotFound)
+
+			var target *MultiError
+			assert.ErrorAs(t, err, &target)
+		})
+	}
+}
+
+func TestTreeFormatWithNestedErrors(t *testing.T) {
+	joined := errors.Join(errors.New("name is empty"), fmt.Errorf("age: %w", errors.New("negative")))
+	errs := []error{fmt.Errorf("validation: %w", joined), errors.New("timeout")}
+
+	expected := "2 errors occurred:\n" +
+		"* validation: name is empty\n" +
+		"  age: negative\n" +
+		"  * 2 errors occurred:\n" +
+		"    * name is empty\n" +
+		"    * age: negative\n" +
+		"      * negative\n" +
+		"* timeout\n"
+	assert.Equal(t, expected, TreeFormat(errs))
+
+	assert.Equal(t,
+		"2 errors occurred: validation: name is empty age: negative; timeout",
+		SingleLineFormat(errs))
+	assert.Equal(t,
+		"2 errors occurred: [2 errors occurred: name is empty; age: negative]; timeout",
+		SingleLineFormat([]error{joined, errors.New("timeout")}))
+}
+
+func TestJSONFormat(t *testing.T) {
+	merr := &MultiError{}
+	merr.Append(fmt.Errorf("load config: %w", errors.New("file not found")))
+	merr.Append(errors.New("timeout"))
+
+	var description errorDescription
+	require.NoError(t, json.Unmarshal([]byte(JSONFormat(merr.errs)), &description))
+
+	assert.Equal(t, errorDescription{
+		Type:    "*main.MultiError",
+		Message: merr.Error(),
+		Errors: []errorDescription{
+			{
+				Type:    "*fmt.wrapError",
+				Message: "load config: file not found",
+				Wrapped: &errorDescription{Type: "*errors.errorString", Message: "file not found"},
+			},
+			{Type: "*errors.errorString", Message: "timeout"},
+		},
+	}, description)
+
+	data, err := json.Marshal(merr)
+	require.NoError(t, err)
+	assert.JSONEq(t, JSONFormat(merr.errs), string(data))
+}
+
+func TestMultiErrorFormatter(t *testing.T) {
+	merr := Append(pkgerrors.New("error 1"), pkgerrors.New("error 2"))
+
+	assert.Equal(t, merr.Error(), fmt.Sprintf("%v", merr))
+	assert.Equal(t, merr.Error(), fmt.Sprintf("%s", merr))
+	assert.Equal(t, fmt.Sprintf("%q", merr.Error()), fmt.Sprintf("%q", merr))
+
+	verbose := fmt.Sprintf("%+v", merr)
+	assert.True(t, strings.HasPrefix(verbose, "2 errors occurred:\n\t* error 1\n\t  "))
+	assert.Contains(t, verbose, "\t* error 2\n\t  ")
+	assert.Equal(t, 2, strings.Count(verbose, ".TestMultiErrorFormatter\n\t  \t"))
+}
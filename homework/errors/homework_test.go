@@ -24,13 +24,12 @@ func (e *MultiError) Error() string {
 	}
 
 	sb := strings.Builder{}
-	sb.WriteString(fmt.Sprintf("%d errors occured:\n", len(e.errs)))
+	sb.WriteString(fmt.Sprintf("%d errors occurred:\n", len(e.errs)))
 
 	for _, err := range e.errs {
-		sb.WriteString(fmt.Sprintf("\t* %v", err))
+		sb.WriteString(fmt.Sprintf("\t* %v\n", err))
 	}
 
-	sb.WriteByte('\n')
 	return sb.String()
 }
 
@@ -63,7 +62,7 @@ func TestMultiError(t *testing.T) {
 	err = Append(err, errors.New("error 1"))
 	err = Append(err, errors.New("error 2"))
 
-	expectedMessage := "2 errors occured:\n\t* error 1\t* error 2\n"
+	expectedMessage := "2 errors occurred:\n\t* error 1\n\t* error 2\n"
 	assert.EqualError(t, err, expectedMessage)
 
 	err3 := errors.New("error 3")
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v homework_test.go format_test.go stack_test.go panic_test.go
+
+type PanicError struct {
+	Value any
+	stack Stack
+}
+
+func newPanicError(value any) *PanicError {
+	return &PanicError{Value: value, stack: panicStack()}
+}
+
+// panicStack returns the stack starting from the function which panicked,
+// it must be called from the deferred function
+func panicStack() Stack {
+	stack := callers(0)
+	for idx, pc := range stack {
+		if fn := runtime.FuncForPC(pc - 1); fn != nil && fn.Name() == "runtime.gopanic" {
+			stack = stack[idx+1:]
+			break
+		}
+	}
+
+	// skip runtime.panicmem, runtime.sigpanic, runtime.goPanicIndex and so on
+	for len(stack) > 0 {
+		fn := runtime.FuncForPC(stack[0] - 1)
+		if fn == nil || !strings.HasPrefix(fn.Name(), "runtime.") {
+			break
+		}
+		stack = stack[1:]
+	}
+
+	return stack
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns panic value if it is an error, so errors.Is
+// and errors.As work with errors passed to panic
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// IsRuntimeError reports whether panic was raised by runtime: nil pointer
+// dereference, index out of range, division by zero and so on
+func (e *PanicError) IsRuntimeError() bool {
+	_, ok := e.Value.(runtime.Error)
+	return ok
+}
+
+func (e *PanicError) StackTrace() Stack {
+	return e.stack
+}
+
+func (e *PanicError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		_, _ = io.WriteString(s, e.Error())
+		e.stack.Format(s, verb)
+		return
+	}
+	_, _ = io.WriteString(s, e.Error())
+}
+
+// Safe calls action and converts its panic to *PanicError
+func Safe(action func() error) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = newPanicError(value)
+		}
+	}()
+
+	return action()
+}
+
+// SafeGo runs action in new goroutine, the channel receives *PanicError
+// if action panics and is closed when action finishes
+func SafeGo(action func()) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		defer close(result)
+		if err := Safe(func() error { action(); return nil }); err != nil {
+			result <- err
+		}
+	}()
+	return result
+}
+
+// SafeBatch runs jobs concurrently and returns errors and panics of all
+// failed jobs as *MultiError in order of jobs, or nil if all jobs succeeded
+func SafeBatch(jobs ...func() error) error {
+	errs := make([]error, len(jobs))
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(jobs))
+	for idx, job := range jobs {
+		go func() {
+			defer wg.Done()
+			errs[idx] = Safe(job)
+		}()
+	}
+	wg.Wait()
+
+	var res MultiError
+	res.Append(errs...)
+	if len(res.errs) == 0 {
+		return nil
+	}
+	return &res
+}
+
+func divide(lhs, rhs int) int {
+	return lhs / rhs
+}
+
+func TestSafe(t *testing.T) {
+	errCustom := errors.New("custom error")
+
+	var nilMap map[string]int
+	var nilPointer *struct{ value int }
+	index := 10
+
+	tests := map[string]struct {
+		action  func() error
+		message string
+		runtime bool
+	}{
+		"division by zero": {
+			action:  func() error { _ = divide(1, 0); return nil },
+			message: "panic: runtime error: integer divide by zero",
+			runtime: true,
+		},
+		"nil pointer dereference": {
+			action:  func() error { nilPointer.value++; return nil },
+			message: "panic: runtime error: invalid memory address or nil pointer dereference",
+			runtime: true,
+		},
+		"index out of range": {
+			action:  func() error { _ = []int{1, 2, 3}[index]; return nil },
+			message: "panic: runtime error: index out of range [10] with length 3",
+			runtime: true,
+		},
+		"nil map assignment": {
+			action:  func() error { nilMap["key"] = 1; return nil },
+			message: "panic: assignment to entry in nil map",
+			runtime: true,
+		},
+		"panic with string": {
+			action:  func() error { panic("something went wrong") },
+			message: "panic: something went wrong",
+		},
+		"panic with error": {
+			action:  func() error { panic(errCustom) },
+			message: "panic: custom error",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := Safe(test.action)
+
+			var panicErr *PanicError
+			require.ErrorAs(t, err, &panicErr)
+			assert.EqualError(t, err, test.message)
+			assert.Equal(t, test.runtime, panicErr.IsRuntimeError())
+
+			var runtimeErr runtime.Error
+			assert.Equal(t, test.runtime, errors.As(err, &runtimeErr))
+		})
+	}
+
+	var panicNilErr *runtime.PanicNilError
+	assert.ErrorAs(t, Safe(func() error { panic(nil) }), &panicNilErr)
+
+	assert.ErrorIs(t, Safe(tests["panic with error"].action), errCustom)
+	assert.NoError(t, Safe(func() error { return nil }))
+	assert.Equal(t, errCustom, Safe(func() error { return errCustom }))
+}
+
+func TestPanicStack(t *testing.T) {
+	err := Safe(func() error { return errors.New(fmt.Sprint(divide(1, 0))) })
+
+	var panicErr *PanicError
+	require.ErrorAs(t, err, &panicErr)
+
+	frames := panicErr.StackTrace().Frames()
+	require.NotEmpty(t, frames)
+	assert.True(t, strings.HasSuffix(frames[0].Function, ".divide"), frames[0].Function)
+
+	verbose := fmt.Sprintf("%+v", err)
+	assert.True(t, strings.HasPrefix(verbose, "panic: runtime error: integer divide by zero\n"))
+	assert.Contains(t, verbose, ".TestPanicStack")
+	assert.Len(t, StackTraces(Append(err, loadUser())), 2)
+}
+
+func TestSafeGo(t *testing.T) {
+	err, ok := <-SafeGo(func() { panic("worker failed") })
+	assert.True(t, ok)
+	assert.EqualError(t, err, "panic: worker failed")
+
+	_, ok = <-SafeGo(func() {})
+	assert.False(t, ok)
+}
+
+func TestSafeBatch(t *testing.T) {
+	errInvalid := errors.New("invalid record")
+
+	err := SafeBatch(
+		func() error { return nil },
+		func() error { return errInvalid },
+		func() error { _ = divide(1, 0); return nil },
+		func() error { panic("unexpected state") },
+	)
+
+	expectedMessage := "3 errors occurred:\n" +
+		"\t* invalid record\n" +
+		"\t* panic: runtime error: integer divide by zero\n" +
+		"\t* panic: unexpected state\n"
+	assert.EqualError(t, err, expectedMessage)
+	assert.ErrorIs(t, err, errInvalid)
+
+	var panicErr *PanicError
+	assert.ErrorAs(t, err, &panicErr)
+
+	assert.NoError(t, SafeBatch(func() error { return nil }))
+	assert.NoError(t, SafeBatch())
+}
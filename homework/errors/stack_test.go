@@ -0,0 +1,286 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v homework_test.go format_test.go stack_test.go
+// go test -bench=. homework_test.go format_test.go stack_test.go
+
+const maxStackDepth = 32
+
+// Stack keeps only program counters, functions and lines
+// are resolved when the stack is printed
+type Stack []uintptr
+
+func callers(skip int) Stack {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	stack := make(Stack, n)
+	copy(stack, pcs[:n])
+	return stack
+}
+
+func (s Stack) Frames() []runtime.Frame {
+	if len(s) == 0 {
+		return nil
+	}
+
+	frames := make([]runtime.Frame, 0, len(s))
+	iterator := runtime.CallersFrames(s)
+	for {
+		frame, more := iterator.Next()
+		frames = append(frames, frame)
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+func (s Stack) Format(st fmt.State, verb rune) {
+	if verb != 'v' || !st.Flag('+') {
+		_, _ = fmt.Fprintf(st, "%v", []uintptr(s))
+		return
+	}
+
+	for _, frame := range s.Frames() {
+		_, _ = fmt.Fprintf(st, "\n%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+	}
+}
+
+type stackError struct {
+	err   error
+	stack Stack
+}
+
+// Errorf works like fmt.Errorf and records the stack of the caller
+func Errorf(format string, args ...any) error {
+	return &stackError{err: fmt.Errorf(format, args...), stack: callers(1)}
+}
+
+// WithStack records the stack of the caller, errors that already
+// have the stack are returned as is
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*stackError); ok {
+		return err
+	}
+	return &stackError{err: err, stack: callers(1)}
+}
+
+func (e *stackError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stackError) Unwrap() error {
+	return e.err
+}
+
+func (e *stackError) StackTrace() Stack {
+	return e.stack
+}
+
+func (e *stackError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			_, _ = io.WriteString(s, e.Error())
+			e.stack.Format(s, verb)
+			for _, cause := range stackCauses(e.err) {
+				_, _ = fmt.Fprintf(s, "\ncaused by: %+v", cause)
+			}
+			return
+		}
+		fallthrough
+	case 's':
+		_, _ = io.WriteString(s, e.Error())
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", e.Error())
+	default:
+		_, _ = fmt.Fprintf(s, "%%!%c(*main.stackError=%s)", verb, e.Error())
+	}
+}
+
+type stackTracer interface {
+	StackTrace() Stack
+}
+
+type pkgStackTracer interface {
+	StackTrace() pkgerrors.StackTrace
+}
+
+// StackTraces returns stacks of all errors in the tree in depth-first order,
+// stacks of github.com/pkg/errors are supported too
+func StackTraces(err error) []Stack {
+	var stacks []Stack
+	var walk func(error)
+	walk = func(err error) {
+		switch e := err.(type) {
+		case stackTracer:
+			stacks = append(stacks, e.StackTrace())
+		case pkgStackTracer:
+			stack := make(Stack, 0, len(e.StackTrace()))
+			for _, frame := range e.StackTrace() {
+				stack = append(stack, uintptr(frame))
+			}
+			stacks = append(stacks, stack)
+		}
+
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, child := range e.Unwrap() {
+				walk(child)
+			}
+		case interface{ Unwrap() error }:
+			walk(e.Unwrap())
+		}
+	}
+
+	walk(err)
+	return stacks
+}
+
+// stackCauses returns the nearest errors with stacks below err
+func stackCauses(err error) []error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case stackTracer, pkgStackTracer:
+		return []error{err}
+	case interface{ Unwrap() []error }:
+		var causes []error
+		for _, child := range e.Unwrap() {
+			causes = append(causes, stackCauses(child)...)
+		}
+		return causes
+	case interface{ Unwrap() error }:
+		return stackCauses(e.Unwrap())
+	default:
+		return nil
+	}
+}
+
+func loadUser() error {
+	return Errorf("user %d not found", 42)
+}
+
+func loadOrder() error {
+	return WithStack(errors.New("order not found"))
+}
+
+func TestStackTrace(t *testing.T) {
+	err := fmt.Errorf("handler: %w", loadUser())
+	assert.EqualError(t, err, "handler: user 42 not found")
+
+	stacks := StackTraces(err)
+	require.Len(t, stacks, 1)
+
+	frames := stacks[0].Frames()
+	assert.True(t, strings.HasSuffix(frames[0].Function, ".loadUser"))
+	assert.True(t, strings.HasSuffix(frames[1].Function, ".TestStackTrace"))
+	assert.True(t, strings.HasSuffix(frames[0].File, "stack_test.go"))
+}
+
+func TestWithStack(t *testing.T) {
+	assert.NoError(t, WithStack(nil))
+
+	err := loadOrder()
+	assert.Same(t, err, WithStack(err))
+
+	var target *stackError
+	require.ErrorAs(t, err, &target)
+	assert.True(t, strings.HasSuffix(target.StackTrace().Frames()[0].Function, ".loadOrder"))
+}
+
+func TestStackTracesInTrees(t *testing.T) {
+	errPlain := errors.New("plain error")
+
+	tests := map[string]struct {
+		err    error
+		stacks int
+	}{
+		"joined errors":   {err: errors.Join(loadUser(), errPlain, loadOrder()), stacks: 2},
+		"multi error":     {err: Append(loadUser(), loadOrder(), errPlain), stacks: 2},
+		"wrapped multi":   {err: Errorf("batch: %w", Append(loadUser(), errPlain)), stacks: 2},
+		"pkg errors":      {err: Append(pkgerrors.New("error"), loadOrder()), stacks: 2},
+		"without stacks":  {err: Append(errPlain, errors.New("other error")), stacks: 0},
+		"double wrapping": {err: Errorf("handler: %w", Errorf("service: %w", loadUser())), stacks: 3},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Len(t, StackTraces(test.err), test.stacks)
+		})
+	}
+}
+
+func TestStackFormatting(t *testing.T) {
+	err := Errorf("handler: %w", loadUser())
+
+	assert.Equal(t, "handler: user 42 not found", fmt.Sprintf("%v", err))
+	assert.Equal(t, "handler: user 42 not found", fmt.Sprintf("%s", err))
+	assert.Equal(t, `"handler: user 42 not found"`, fmt.Sprintf("%q", err))
+
+	verbose := fmt.Sprintf("%+v", err)
+	assert.True(t, strings.HasPrefix(verbose, "handler: user 42 not found\n"))
+	assert.Contains(t, verbose, "\ncaused by: user 42 not found\n")
+	assert.Contains(t, verbose, ".loadUser\n\t")
+	assert.Equal(t, 2, strings.Count(verbose, ".TestStackFormatting\n\t"))
+
+	merr := fmt.Sprintf("%+v", Append(loadUser(), loadOrder()))
+	assert.Contains(t, merr, "\t* user 42 not found\n\t  ")
+	assert.Contains(t, merr, ".loadUser\n\t  \t")
+	assert.Contains(t, merr, ".loadOrder\n\t  \t")
+}
+
+func TestEmptyStack(t *testing.T) {
+	var stack Stack
+	assert.Nil(t, stack.Frames())
+	assert.Empty(t, fmt.Sprintf("%+v", stack))
+}
+
+var stackErr error
+
+func BenchmarkErrorsNew(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		stackErr = errors.New("error")
+	}
+}
+
+func BenchmarkErrorfWithStack(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		stackErr = Errorf("error")
+	}
+}
+
+func BenchmarkWithStack(b *testing.B) {
+	err := errors.New("error")
+	for i := 0; i < b.N; i++ {
+		stackErr = WithStack(err)
+	}
+}
+
+func BenchmarkPkgErrorsNew(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		stackErr = pkgerrors.New("error")
+	}
+}
+
+func BenchmarkStackFormatting(b *testing.B) {
+	err := Errorf("error")
+	for i := 0; i < b.N; i++ {
+		_ = fmt.Sprintf("%+v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"iter"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go iterators_test.go
+// go test -bench=. -benchmem homework_test.go iterators_test.go
+
+// Lazy versions of Map, Filter and Reduce work with iter.Seq, every element
+// passes the whole chain before the next one is requested from the source,
+// so stages don't allocate intermediate slices.
+
+func MapSeq[T, U any](seq iter.Seq[T], action func(T) U) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range seq {
+			if !yield(action(v)) {
+				return
+			}
+		}
+	}
+}
+
+func FilterSeq[T any](seq iter.Seq[T], action func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			if action(v) && !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func FlatMapSeq[T, U any](seq iter.Seq[T], action func(T) iter.Seq[U]) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range seq {
+			for u := range action(v) {
+				if !yield(u) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func ReduceSeq[T, A any](seq iter.Seq[T], initial A, action func(A, T) A) A {
+	for v := range seq {
+		initial = action(initial, v)
+	}
+	return initial
+}
+
+func Take[T any](seq iter.Seq[T], count int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if count <= 0 {
+			return
+		}
+
+		taken := 0
+		for v := range seq {
+			if !yield(v) {
+				return
+			}
+			if taken++; taken == count {
+				return
+			}
+		}
+	}
+}
+
+func Skip[T any](seq iter.Seq[T], count int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		skipped := 0
+		for v := range seq {
+			if skipped < count {
+				skipped++
+				continue
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Chunk yields new slice for every chunk, the last chunk may be shorter
+func Chunk[T any](seq iter.Seq[T], size int) iter.Seq[[]T] {
+	if size <= 0 {
+		panic("chunk size must be positive")
+	}
+
+	return func(yield func([]T) bool) {
+		chunk := make([]T, 0, size)
+		for v := range seq {
+			chunk = append(chunk, v)
+			if len(chunk) == size {
+				if !yield(chunk) {
+					return
+				}
+				chunk = make([]T, 0, size)
+			}
+		}
+
+		if len(chunk) > 0 {
+			yield(chunk)
+		}
+	}
+}
+
+// Zip yields pairs until the shortest sequence is over
+func Zip[T, U any](lhs iter.Seq[T], rhs iter.Seq[U]) iter.Seq2[T, U] {
+	return func(yield func(T, U) bool) {
+		next, stop := iter.Pull(rhs)
+		defer stop()
+
+		for l := range lhs {
+			r, ok := next()
+			if !ok || !yield(l, r) {
+				return
+			}
+		}
+	}
+}
+
+func naturals() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 1; yield(i); i++ {
+		}
+	}
+}
+
+func TestLazyPipeline(t *testing.T) {
+	tests := map[string]struct {
+		seq    iter.Seq[string]
+		result []string
+	}{
+		"map with other type": {
+			seq:    MapSeq(slices.Values([]int{1, 2, 3}), strconv.Itoa),
+			result: []string{"1", "2", "3"},
+		},
+		"filter and map": {
+			seq: MapSeq(FilterSeq(slices.Values([]int{1, 2, 3, 4, 5}), func(number int) bool {
+				return number%2 == 1
+			}), strconv.Itoa),
+			result: []string{"1", "3", "5"},
+		},
+		"flat map": {
+			seq: FlatMapSeq(slices.Values([]int{1, 2, 3}), func(number int) iter.Seq[string] {
+				return slices.Values(slices.Repeat([]string{strconv.Itoa(number)}, number))
+			}),
+			result: []string{"1", "2", "2", "3", "3", "3"},
+		},
+		"skip and take from infinite sequence": {
+			seq:    MapSeq(Take(Skip(naturals(), 10), 3), strconv.Itoa),
+			result: []string{"11", "12", "13"},
+		},
+		"take nothing": {
+			seq: MapSeq(Take(naturals(), 0), strconv.Itoa),
+		},
+		"take more than available": {
+			seq:    Take(slices.Values([]string{"a", "b"}), 5),
+			result: []string{"a", "b"},
+		},
+		"skip everything": {
+			seq: Skip(slices.Values([]string{"a", "b"}), 5),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := slices.Collect(test.seq)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestLazyEvaluation(t *testing.T) {
+	var calls int
+	seq := MapSeq(naturals(), func(number int) int {
+		calls++
+		return number * number
+	})
+	assert.Zero(t, calls)
+
+	result := slices.Collect(Take(FilterSeq(seq, func(number int) bool { return number%2 == 0 }), 2))
+	assert.Equal(t, []int{4, 16}, result)
+	assert.Equal(t, 4, calls)
+}
+
+func TestChunk(t *testing.T) {
+	chunks := slices.Collect(Chunk(slices.Values([]int{1, 2, 3, 4, 5}), 2))
+	assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, chunks)
+
+	chunks = slices.Collect(Take(Chunk(naturals(), 3), 2))
+	assert.Equal(t, [][]int{{1, 2, 3}, {4, 5, 6}}, chunks)
+
+	assert.Empty(t, slices.Collect(Chunk(slices.Values([]int{}), 2)))
+	assert.Panics(t, func() { Chunk(naturals(), 0) })
+}
+
+func TestZip(t *testing.T) {
+	var names []string
+	var ages []int
+	for name, age := range Zip(slices.Values([]string{"Ann", "Bob", "Eve"}), naturals()) {
+		names = append(names, name)
+		ages = append(ages, age)
+	}
+	assert.Equal(t, []string{"Ann", "Bob", "Eve"}, names)
+	assert.Equal(t, []int{1, 2, 3}, ages)
+
+	var count int
+	for range Zip(naturals(), slices.Values([]int{1, 2})) {
+		count++
+	}
+	assert.Equal(t, 2, count)
+}
+
+func TestReduceSeq(t *testing.T) {
+	words := slices.Values([]string{"lazy", "evaluation", "in", "go"})
+
+	length := ReduceSeq(words, 0, func(sum int, word string) int {
+		return sum + len(word)
+	})
+	assert.Equal(t, 18, length)
+
+	lengths := ReduceSeq(words, map[string]int{}, func(acc map[string]int, word string) map[string]int {
+		acc[word] = len(word)
+		return acc
+	})
+	assert.Equal(t, map[string]int{"lazy": 4, "evaluation": 10, "in": 2, "go": 2}, lengths)
+
+	assert.Equal(t, 10, ReduceSeq(slices.Values([]int(nil)), 10, func(lhs, rhs int) int { return lhs + rhs }))
+}
+
+var benchmarkData = func() []int {
+	data := make([]int, 10_000)
+	for i := range data {
+		data[i] = i
+	}
+	return data
+}()
+
+var benchmarkResult int
+
+func BenchmarkEagerPipeline(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		squares := Map(benchmarkData, func(number int) int { return number * number })
+		even := Filter(squares, func(number int) bool { return number%2 == 0 })
+		benchmarkResult = Reduce(even, 0, func(lhs, rhs int) int { return lhs + rhs })
+	}
+}
+
+func BenchmarkLazyPipeline(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		squares := MapSeq(slices.Values(benchmarkData), func(number int) int { return number * number })
+		even := FilterSeq(squares, func(number int) bool { return number%2 == 0 })
+		benchmarkResult = ReduceSeq(even, 0, func(lhs, rhs int) int { return lhs + rhs })
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v -race homework_test.go parallel_test.go
+// go test -bench=Parallel -benchmem homework_test.go parallel_test.go
+
+const (
+	// inputs shorter than sequentialThreshold are processed by the calling goroutine
+	sequentialThreshold = 1024
+	minChunkSize        = 256
+	// chunksPerWorker makes chunks smaller than len/workers, so fast workers
+	// take chunks left by slow ones
+	chunksPerWorker = 4
+)
+
+// ParallelMap applies action to every element preserving order of results,
+// it stops on the first error or when ctx is cancelled. Non-positive number
+// of workers means runtime.GOMAXPROCS(0).
+func ParallelMap[T, U any](ctx context.Context, data []T, action func(T) (U, error), workers int) ([]U, error) {
+	if data == nil {
+		return nil, ctx.Err()
+	}
+
+	out := make([]U, len(data))
+	err := processChunks(ctx, len(data), workers, func(_, from, to int) error {
+		for idx := from; idx < to; idx++ {
+			value, err := action(data[idx])
+			if err != nil {
+				return err
+			}
+			out[idx] = value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ParallelReduce reduces chunks concurrently and then combines partial results
+// in order, so action must be associative but is not required to be commutative
+func ParallelReduce[T any](ctx context.Context, data []T, initial T, action func(T, T) T, workers int) (T, error) {
+	chunks, _ := splitChunks(len(data), workers)
+	partials := make([]T, chunks)
+	err := processChunks(ctx, len(data), workers, func(chunk, from, to int) error {
+		partial := data[from]
+		for _, v := range data[from+1 : to] {
+			partial = action(partial, v)
+		}
+		partials[chunk] = partial
+		return nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	for _, partial := range partials {
+		initial = action(initial, partial)
+	}
+	return initial, nil
+}
+
+func splitChunks(length, workers int) (chunks, chunkSize int) {
+	if length == 0 {
+		return 0, 0
+	}
+
+	chunkSize = max(length/(workersCount(workers)*chunksPerWorker), minChunkSize)
+	return (length + chunkSize - 1) / chunkSize, chunkSize
+}
+
+func workersCount(workers int) int {
+	if workers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return workers
+}
+
+func processChunks(ctx context.Context, length, workers int, process func(chunk, from, to int) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	chunks, chunkSize := splitChunks(length, workers)
+	workers = min(workersCount(workers), chunks)
+	if length < sequentialThreshold || workers == 1 {
+		for chunk := range chunks {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			from := chunk * chunkSize
+			if err := process(chunk, from, min(from+chunkSize, length)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				chunk := int(next.Add(1) - 1)
+				if chunk >= chunks {
+					return
+				}
+
+				from := chunk * chunkSize
+				if err := process(chunk, from, min(from+chunkSize, length)); err != nil {
+					cancel(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return context.Cause(ctx)
+}
+
+func TestParallelMap(t *testing.T) {
+	tests := map[string]struct {
+		length  int
+		workers int
+	}{
+		"nil data":                 {length: -1, workers: 4},
+		"empty data":               {length: 0, workers: 4},
+		"sequential path":          {length: sequentialThreshold - 1, workers: 4},
+		"single worker":            {length: 10_000, workers: 1},
+		"default workers":          {length: 10_000, workers: 0},
+		"more workers":             {length: 10_000, workers: 64},
+		"uneven last chunk":        {length: 10_007, workers: 3},
+		"workers more than chunks": {length: sequentialThreshold, workers: 100},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var data []int
+			var expected []string
+			if test.length >= 0 {
+				data = make([]int, test.length)
+				expected = make([]string, test.length)
+				for i := range data {
+					data[i] = i
+					expected[i] = strconv.Itoa(i)
+				}
+			}
+
+			action := func(number int) (string, error) { return strconv.Itoa(number), nil }
+			result, err := ParallelMap(context.Background(), data, action, test.workers)
+			require.NoError(t, err)
+			assert.Equal(t, expected, result)
+		})
+	}
+}
+
+func TestParallelMapStopsOnError(t *testing.T) {
+	errInvalid := errors.New("invalid number")
+	data := make([]int, 100_000)
+	data[2000] = -1
+
+	var calls atomic.Int64
+	result, err := ParallelMap(context.Background(), data, func(number int) (int, error) {
+		calls.Add(1)
+		if number < 0 {
+			return 0, errInvalid
+		}
+		return number, nil
+	}, 4)
+
+	assert.ErrorIs(t, err, errInvalid)
+	assert.Nil(t, result)
+	assert.Less(t, calls.Load(), int64(len(data)))
+}
+
+func TestParallelMapStopsOnCancel(t *testing.T) {
+	data := make([]int, 100_000)
+	for _, workers := range []int{1, 4} {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var calls atomic.Int64
+		_, err := ParallelMap(ctx, data, func(number int) (int, error) {
+			if calls.Add(1) == 1000 {
+				cancel()
+			}
+			return number, nil
+		}, workers)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, calls.Load(), int64(len(data)))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ParallelMap(ctx, []int{1}, func(number int) (int, error) { return number, nil }, 4)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestParallelReduce(t *testing.T) {
+	for _, length := range []int{0, 1, 10, sequentialThreshold, 10_007} {
+		data := make([]string, length)
+		for i := range data {
+			data[i] = strconv.Itoa(i % 10)
+		}
+
+		// concatenation is associative but not commutative, so the order matters
+		concat := func(lhs, rhs string) string { return lhs + rhs }
+		for _, workers := range []int{0, 1, 3} {
+			result, err := ParallelReduce(context.Background(), data, ">", concat, workers)
+			require.NoError(t, err)
+			assert.Equal(t, Reduce(data, ">", concat), result)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ParallelReduce(ctx, []int{1, 2, 3}, 0, func(lhs, rhs int) int { return lhs + rhs }, 4)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+var parallelBenchmarkData = func() []float64 {
+	data := make([]float64, 100_000)
+	for i := range data {
+		data[i] = float64(i)
+	}
+	return data
+}()
+
+var parallelBenchmarkResult []float64
+
+func heavyTransform(number float64) float64 {
+	for range 100 {
+		number = math.Sqrt(number*number + 1)
+	}
+	return number
+}
+
+func BenchmarkSequentialMap(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		parallelBenchmarkResult = Map(parallelBenchmarkData, heavyTransform)
+	}
+}
+
+func BenchmarkParallelMap(b *testing.B) {
+	action := func(number float64) (float64, error) { return heavyTransform(number), nil }
+	for i := 0; i < b.N; i++ {
+		parallelBenchmarkResult, _ = ParallelMap(context.Background(), parallelBenchmarkData, action, 0)
+	}
+}
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"maps"
+	"math/bits"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v homework_test.go heap_test.go generations_test.go
+
+// slot is a pointer field of the object
+type slot struct {
+	addr  uintptr
+	field int
+}
+
+// PhaseStats reports work of the phase in units: a root or a field scanned,
+// an object forwarded, freed or a word copied, it is equivalent of the pause
+type PhaseStats struct {
+	Name string
+	Work int
+}
+
+type GCStats struct {
+	Phases       []PhaseStats
+	Promoted     int
+	FreedObjects int
+	LiveBytes    int
+}
+
+func (s GCStats) Work() int {
+	var work int
+	for _, phase := range s.Phases {
+		work += phase.Work
+	}
+	return work
+}
+
+// GenerationalHeap allocates objects in the young generation, survivors of
+// minor collections are promoted to the old one. Objects are moved, so stacks
+// are scanned precisely: words equal to addresses of objects are rewritten.
+type GenerationalHeap struct {
+	young      *Heap
+	old        *Heap
+	globals    []uintptr
+	remembered map[slot]struct{} // fields of old objects which point to young ones
+}
+
+func NewGenerationalHeap(youngWords, oldWords int) *GenerationalHeap {
+	return &GenerationalHeap{
+		young:      NewHeap(youngWords),
+		old:        NewHeap(oldWords),
+		remembered: make(map[slot]struct{}),
+	}
+}
+
+func (g *GenerationalHeap) IsYoung(addr uintptr) bool {
+	_, ok := g.young.objects[addr]
+	return ok
+}
+
+func (g *GenerationalHeap) IsOld(addr uintptr) bool {
+	_, ok := g.old.objects[addr]
+	return ok
+}
+
+func (g *GenerationalHeap) heapOf(addr uintptr) *Heap {
+	switch {
+	case g.IsYoung(addr):
+		return g.young
+	case g.IsOld(addr):
+		return g.old
+	default:
+		panic("gc: invalid object address")
+	}
+}
+
+// Allocate returns ErrOutOfMemory when the young generation is full,
+// MinorCollect must be called to free it
+func (g *GenerationalHeap) Allocate(size int, pointers uint64) (uintptr, error) {
+	return g.young.Allocate(size, pointers)
+}
+
+func (g *GenerationalHeap) Load(addr uintptr, field int) uintptr {
+	return g.heapOf(addr).Load(addr, field)
+}
+
+// Store remembers pointers from old objects to young ones,
+// they are roots of minor collections
+func (g *GenerationalHeap) Store(addr uintptr, field int, value uintptr) {
+	heap := g.heapOf(addr)
+	object, word := heap.field(addr, field)
+	if object.Pointers&(1<<field) != 0 {
+		if value != 0 && !g.IsYoung(value) && !g.IsOld(value) {
+			panic("gc: pointer to unknown object")
+		}
+		if heap == g.old && g.IsYoung(value) {
+			g.remembered[slot{addr: addr, field: field}] = struct{}{}
+		}
+	}
+	*word = value
+}
+
+func (g *GenerationalHeap) AddGlobal(addr uintptr) int {
+	g.globals = append(g.globals, addr)
+	return len(g.globals) - 1
+}
+
+func (g *GenerationalHeap) SetGlobal(idx int, addr uintptr) {
+	g.globals[idx] = addr
+}
+
+func (g *GenerationalHeap) Global(idx int) uintptr {
+	return g.globals[idx]
+}
+
+// updateRoots replaces roots of stacks and globals with update result
+// and returns number of scanned words
+func (g *GenerationalHeap) updateRoots(stacks [][]uintptr, update func(uintptr) uintptr) int {
+	var work int
+	for _, words := range append(slices.Clone(stacks), g.globals) {
+		for idx, word := range words {
+			words[idx] = update(word)
+			work++
+		}
+	}
+	return work
+}
+
+// updateFields replaces pointer fields of objects of the heap with update
+// result and returns number of scanned fields
+func updateFields(heap *Heap, objects []*Object, update func(uintptr) uintptr) int {
+	var work int
+	for _, object := range objects {
+		offset := heap.offset(object.Addr)
+		for field := range object.Size {
+			if object.Pointers&(1<<field) != 0 {
+				heap.arena[offset+field] = update(heap.arena[offset+field])
+				work++
+			}
+		}
+	}
+	return work
+}
+
+func (h *Heap) usedWords() int {
+	return h.LiveBytes() / wordSize
+}
+
+func (h *Heap) largestFreeSpan() int {
+	var largest int
+	for _, free := range h.free {
+		largest = max(largest, free.size)
+	}
+	return largest
+}
+
+// MinorCollect copies young objects reachable from roots and remembered
+// fields to the old generation and frees the young generation. Old generation
+// is compacted first if it may not have enough space for survivors.
+func (g *GenerationalHeap) MinorCollect(stacks [][]uintptr) (GCStats, error) {
+	var stats GCStats
+	if g.old.largestFreeSpan() < g.young.usedWords() {
+		stats = g.MajorCollect(stacks)
+		if g.old.largestFreeSpan() < g.young.usedWords() {
+			return stats, ErrOutOfMemory
+		}
+	}
+
+	var copyWork int
+	var promoted []*Object
+	forwarding := make(map[uintptr]uintptr)
+	forward := func(addr uintptr) uintptr {
+		object, ok := g.young.objects[addr]
+		if !ok {
+			return addr
+		}
+		if to, ok := forwarding[addr]; ok {
+			return to
+		}
+
+		to, _ := g.old.Allocate(object.Size, object.Pointers)
+		from := g.young.offset(addr)
+		copy(g.old.arena[g.old.offset(to):], g.young.arena[from:from+object.Size])
+		copyWork += object.Size
+
+		forwarding[addr] = to
+		promoted = append(promoted, g.old.objects[to])
+		return to
+	}
+
+	rootsWork := g.updateRoots(stacks, forward)
+	for remembered := range g.remembered {
+		word := &g.old.arena[g.old.offset(remembered.addr)+remembered.field]
+		*word = forward(*word)
+		rootsWork++
+	}
+
+	// Cheney-like scan, promoted objects may point to young ones
+	for scanned := 0; scanned < len(promoted); scanned++ {
+		copyWork += updateFields(g.old, promoted[scanned:scanned+1], forward)
+	}
+
+	young := g.young.Len()
+	g.young.sweep(newMarkBits(len(g.young.arena)))
+	clear(g.remembered)
+
+	stats.Phases = append(stats.Phases,
+		PhaseStats{Name: "roots", Work: rootsWork},
+		PhaseStats{Name: "copy", Work: copyWork},
+		PhaseStats{Name: "reset", Work: young},
+	)
+	stats.Promoted = len(promoted)
+	stats.FreedObjects += young - len(promoted)
+	stats.LiveBytes = g.old.LiveBytes()
+	return stats, nil
+}
+
+// MajorCollect marks the old generation and slides live objects to the start
+// of its arena like Defragment of the allocator homework, so the free memory
+// is a single span. Young objects are treated as live, their pointers are roots.
+func (g *GenerationalHeap) MajorCollect(stacks [][]uintptr) GCStats {
+	var markWork int
+	roots := g.old.roots(stacks)
+	for _, words := range append(slices.Clone(stacks), g.globals) {
+		markWork += len(words)
+	}
+
+	young := g.young.sortedObjects()
+	for _, object := range young {
+		g.young.children(object, func(child uintptr) {
+			if object, ok := g.old.objects[child]; ok {
+				roots = append(roots, object)
+			}
+		})
+		markWork++
+	}
+	roots = append(roots, g.old.roots([][]uintptr{g.globals})...)
+	marks := g.old.mark(roots)
+
+	// forwarding addresses are assigned in address order,
+	// so objects are moved only to lower addresses
+	var live []*Object
+	var stats GCStats
+	forwarding := make(map[uintptr]uintptr)
+	cursor := 0
+	for _, object := range g.old.sortedObjects() {
+		if !marks.test(g.old.offset(object.Addr)) {
+			stats.FreedObjects++
+			continue
+		}
+
+		markWork += 1 + bits.OnesCount64(object.Pointers)
+		forwarding[object.Addr] = g.old.address(cursor)
+		cursor += object.Size
+		live = append(live, object)
+	}
+
+	forward := func(addr uintptr) uintptr {
+		if to, ok := forwarding[addr]; ok {
+			return to
+		}
+		return addr
+	}
+	updateWork := g.updateRoots(stacks, forward)
+	updateWork += updateFields(g.old, live, forward)
+	updateWork += updateFields(g.young, young, forward)
+
+	remembered := make(map[slot]struct{}, len(g.remembered))
+	for field := range g.remembered {
+		if to, ok := forwarding[field.addr]; ok {
+			remembered[slot{addr: to, field: field.field}] = struct{}{}
+		}
+		updateWork++
+	}
+	g.remembered = remembered
+
+	moveWork := g.old.slide(live, forwarding)
+	stats.Phases = []PhaseStats{
+		{Name: "mark", Work: markWork},
+		{Name: "forward", Work: len(live) + stats.FreedObjects},
+		{Name: "update", Work: updateWork},
+		{Name: "move", Work: moveWork},
+	}
+	stats.LiveBytes = g.old.LiveBytes()
+	return stats
+}
+
+// slide moves live objects to forwarding addresses in address order,
+// frees the rest of the arena and returns number of moved words
+func (h *Heap) slide(live []*Object, forwarding map[uintptr]uintptr) int {
+	var moved, end int
+	objects := make(map[uintptr]*Object, len(live))
+	for _, object := range live {
+		from, to := h.offset(object.Addr), h.offset(forwarding[object.Addr])
+		if from != to {
+			copy(h.arena[to:to+object.Size], h.arena[from:from+object.Size])
+			moved += object.Size
+		}
+
+		object.Addr = forwarding[object.Addr]
+		objects[object.Addr] = object
+		end = to + object.Size
+	}
+
+	clear(h.arena[end:])
+	h.objects = objects
+	h.free = h.free[:0]
+	if end < len(h.arena) {
+		h.free = append(h.free, span{offset: end, size: len(h.arena) - end})
+	}
+	return moved
+}
+
+func mustAllocateYoung(t *testing.T, heap *GenerationalHeap, size int, pointers uint64) uintptr {
+	addr, err := heap.Allocate(size, pointers)
+	require.NoError(t, err)
+	return addr
+}
+
+func phaseWork(stats GCStats, name string) int {
+	for _, phase := range stats.Phases {
+		if phase.Name == name {
+			return phase.Work
+		}
+	}
+	return -1
+}
+
+func TestMinorCollect(t *testing.T) {
+	heap := NewGenerationalHeap(64, 64)
+
+	// list {value, next} from the stack: first -> second
+	first := mustAllocateYoung(t, heap, 2, 0b10)
+	second := mustAllocateYoung(t, heap, 2, 0b10)
+	heap.Store(first, 0, 1)
+	heap.Store(first, 1, second)
+	heap.Store(second, 0, 2)
+	global := mustAllocateYoung(t, heap, 1, 0)
+	heap.Store(global, 0, 3)
+	heap.AddGlobal(global)
+	for range 10 {
+		mustAllocateYoung(t, heap, 2, 0b10) // garbage
+	}
+
+	stacks := [][]uintptr{{0x00, first}}
+	stats, err := heap.MinorCollect(stacks)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Promoted)
+	assert.Equal(t, 10, stats.FreedObjects)
+	assert.Equal(t, 5*wordSize, stats.LiveBytes)
+	assert.Equal(t, 5+2, phaseWork(stats, "copy")) // 5 words and 2 pointer fields
+	assert.Zero(t, heap.young.Len())
+
+	// roots are rewritten to promoted objects
+	first, global = stacks[0][1], heap.Global(0)
+	assert.True(t, heap.IsOld(first))
+	assert.True(t, heap.IsOld(global))
+	second = heap.Load(first, 1)
+	assert.True(t, heap.IsOld(second))
+	assert.Equal(t, []uintptr{1, 2, 3}, []uintptr{heap.Load(first, 0), heap.Load(second, 0), heap.Load(global, 0)})
+}
+
+func TestRememberedSet(t *testing.T) {
+	heap := NewGenerationalHeap(64, 64)
+	parent := mustAllocateYoung(t, heap, 2, 0b10)
+	stacks := [][]uintptr{{parent}}
+	_, err := heap.MinorCollect(stacks)
+	require.NoError(t, err)
+	parent = stacks[0][0]
+
+	// child is reachable only from the old object
+	child := mustAllocateYoung(t, heap, 2, 0b10)
+	heap.Store(child, 0, 42)
+	heap.Store(parent, 1, child)
+	assert.Len(t, heap.remembered, 1)
+
+	stats, err := heap.MinorCollect(stacks)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Promoted)
+	assert.Equal(t, 2, phaseWork(stats, "roots")) // the stack slot and the remembered field
+	assert.Empty(t, heap.remembered)
+
+	child = heap.Load(parent, 1)
+	assert.True(t, heap.IsOld(child))
+	assert.Equal(t, uintptr(42), heap.Load(child, 0))
+}
+
+func TestMajorCollect(t *testing.T) {
+	heap := NewGenerationalHeap(64, 12)
+
+	// four old objects {value, pointer}, the first and the third become garbage
+	objects := make([]uintptr, 4)
+	for idx := range objects {
+		objects[idx] = mustAllocateYoung(t, heap, 2, 0b10)
+		heap.Store(objects[idx], 0, uintptr(idx))
+	}
+	heap.Store(objects[1], 1, objects[3])
+	stacks := [][]uintptr{slices.Clone(objects)}
+	_, err := heap.MinorCollect(stacks)
+	require.NoError(t, err)
+	stacks[0][0], stacks[0][2], stacks[0][3] = 0, 0, 0
+
+	// young object points to the old one
+	young := mustAllocateYoung(t, heap, 2, 0b10)
+	heap.Store(young, 1, stacks[0][1])
+	stacks[0] = append(stacks[0], young)
+
+	stats := heap.MajorCollect(stacks)
+	assert.Equal(t, 2, stats.FreedObjects)
+	assert.Equal(t, 4*wordSize, stats.LiveBytes)
+	assert.Equal(t, 4, phaseWork(stats, "move")) // both objects are moved
+	assert.Equal(t, 8*wordSize, heap.old.FreeBytes())
+	assert.Equal(t, 8, heap.old.largestFreeSpan())
+
+	// live objects are packed to the start of the arena, pointers are rewritten
+	second := stacks[0][1]
+	assert.Equal(t, heap.old.address(0), second)
+	fourth := heap.Load(second, 1)
+	assert.Equal(t, heap.old.address(2), fourth)
+	assert.Equal(t, second, heap.Load(young, 1))
+	assert.Equal(t, []uintptr{1, 3}, []uintptr{heap.Load(second, 0), heap.Load(fourth, 0)})
+}
+
+func TestMinorCollectCompactsOldGeneration(t *testing.T) {
+	heap := NewGenerationalHeap(16, 12)
+	stacks := [][]uintptr{make([]uintptr, 4)}
+
+	// every cycle keeps only the last object alive, so the old generation
+	// is full of garbage and has to be compacted
+	for cycle := range 20 {
+		object := mustAllocateYoung(t, heap, 4, 0)
+		heap.Store(object, 0, uintptr(cycle))
+		stacks[0][0] = object
+
+		stats, err := heap.MinorCollect(stacks)
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats.Promoted)
+		assert.Equal(t, uintptr(cycle), heap.Load(stacks[0][0], 0))
+
+		// old generation fits 3 objects, the major collection
+		// frees all of them before the promotion
+		var freed int
+		if cycle > 0 && cycle%3 == 0 {
+			freed = 3
+			assert.Equal(t, "mark", stats.Phases[0].Name)
+		}
+		assert.Equal(t, freed, stats.FreedObjects)
+	}
+
+	heap.MajorCollect(stacks)
+	assert.Equal(t, 1, heap.old.Len())
+
+	// survivors don't fit even after compaction
+	for idx := range stacks[0] {
+		stacks[0][idx] = mustAllocateYoung(t, heap, 4, 0)
+	}
+	stats, err := heap.MinorCollect(stacks)
+	assert.ErrorIs(t, err, ErrOutOfMemory)
+	assert.Equal(t, 1, stats.FreedObjects) // the last promoted object isn't on the stack anymore
+	assert.NotEmpty(t, stats.Phases)
+}
+
+func TestGenerationalHeapLikeTrace(t *testing.T) {
+	// every object is a single pointer like in TestTrace
+	heap := NewGenerationalHeap(256, 256)
+	var stacks [][]uintptr
+	for range 4 {
+		var previous uintptr
+		for range 20 {
+			object := mustAllocateYoung(t, heap, 1, 1)
+			heap.Store(object, 0, previous)
+			previous = object
+		}
+		stacks = append(stacks, []uintptr{previous, 0x00})
+	}
+
+	_, err := heap.MinorCollect(stacks)
+	require.NoError(t, err)
+
+	// the second half of every chain becomes garbage
+	for _, stack := range stacks {
+		object := stack[0]
+		for range 9 {
+			object = heap.Load(object, 0)
+		}
+		heap.Store(object, 0, 0)
+	}
+
+	heap.MajorCollect(stacks)
+	assert.Equal(t, 40, heap.old.Len())
+	assert.ElementsMatch(t, Trace(stacks), slices.Collect(maps.Keys(heap.old.objects)))
+
+	// minor collection work depends on survivors, not on garbage
+	for range 200 {
+		mustAllocateYoung(t, heap, 1, 1)
+	}
+	stats, err := heap.MinorCollect(stacks)
+	require.NoError(t, err)
+	assert.Zero(t, phaseWork(stats, "copy"))
+	assert.Equal(t, 200, stats.FreedObjects)
+}
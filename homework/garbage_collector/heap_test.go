@@ -0,0 +1,412 @@
+package main
+
+import (
+	"errors"
+	"math/bits"
+	"slices"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v homework_test.go heap_test.go
+
+const (
+	wordSize      = int(unsafe.Sizeof(uintptr(0)))
+	maxObjectSize = 64 // pointer bitmap is uint64
+)
+
+var ErrOutOfMemory = errors.New("out of memory")
+
+// Object is a header of the object in the simulated heap, Size
+// is in words and bit i of Pointers is set if word i holds a pointer
+type Object struct {
+	Addr     uintptr
+	Size     int
+	Pointers uint64
+}
+
+type span struct {
+	offset int
+	size   int
+}
+
+// markBits has a bit for every word of the arena,
+// only bits of the first words of objects are used
+type markBits []uint64
+
+func newMarkBits(words int) markBits {
+	return make(markBits, (words+63)/64)
+}
+
+func (m markBits) set(offset int) {
+	m[offset/64] |= 1 << (offset % 64)
+}
+
+func (m markBits) test(offset int) bool {
+	return m[offset/64]&(1<<(offset%64)) != 0
+}
+
+// Barrier is notified about mutations of the heap during marking
+type Barrier interface {
+	WritePointer(old, new uintptr)
+	Allocated(addr uintptr)
+}
+
+// Heap is a simulated heap on top of real memory, so addresses of objects
+// are real addresses of arena words and the arena can be traced by Trace
+type Heap struct {
+	arena   []uintptr
+	base    uintptr
+	objects map[uintptr]*Object
+	free    []span // sorted by offset, adjacent spans are merged
+	globals []uintptr
+	barrier Barrier // is set during incremental marking
+}
+
+func NewHeap(words int) *Heap {
+	if words <= 0 {
+		panic("gc: heap size must be positive")
+	}
+
+	arena := make([]uintptr, words)
+	return &Heap{
+		arena:   arena,
+		base:    uintptr(unsafe.Pointer(unsafe.SliceData(arena))),
+		objects: make(map[uintptr]*Object),
+		free:    []span{{offset: 0, size: words}},
+	}
+}
+
+func (h *Heap) offset(addr uintptr) int {
+	return int(addr-h.base) / wordSize
+}
+
+func (h *Heap) address(offset int) uintptr {
+	return h.base + uintptr(offset*wordSize)
+}
+
+// Allocate finds the first free span large enough for the object,
+// pointer fields of the new object are nil
+func (h *Heap) Allocate(size int, pointers uint64) (uintptr, error) {
+	if size <= 0 || size > maxObjectSize || (size < maxObjectSize && pointers>>size != 0) {
+		panic("gc: invalid object layout")
+	}
+
+	for idx := range h.free {
+		free := &h.free[idx]
+		if free.size < size {
+			continue
+		}
+
+		addr := h.address(free.offset)
+		free.offset += size
+		free.size -= size
+		if free.size == 0 {
+			h.free = slices.Delete(h.free, idx, idx+1)
+		}
+
+		h.objects[addr] = &Object{Addr: addr, Size: size, Pointers: pointers}
+		if h.barrier != nil {
+			h.barrier.Allocated(addr)
+		}
+		return addr, nil
+	}
+
+	return 0, ErrOutOfMemory
+}
+
+func (h *Heap) Object(addr uintptr) (Object, bool) {
+	object, ok := h.objects[addr]
+	if !ok {
+		return Object{}, false
+	}
+	return *object, true
+}
+
+func (h *Heap) Len() int {
+	return len(h.objects)
+}
+
+func (h *Heap) field(addr uintptr, field int) (*Object, *uintptr) {
+	object, ok := h.objects[addr]
+	if !ok {
+		panic("gc: invalid object address")
+	}
+	if field < 0 || field >= object.Size {
+		panic("gc: invalid object field")
+	}
+	return object, &h.arena[h.offset(addr)+field]
+}
+
+func (h *Heap) Load(addr uintptr, field int) uintptr {
+	_, word := h.field(addr, field)
+	return *word
+}
+
+// Store writes the value to the field, pointer fields accept
+// only nil or addresses of objects of the heap
+func (h *Heap) Store(addr uintptr, field int, value uintptr) {
+	object, word := h.field(addr, field)
+	if object.Pointers&(1<<field) == 0 {
+		*word = value
+		return
+	}
+
+	if _, ok := h.objects[value]; !ok && value != 0 {
+		panic("gc: pointer to unknown object")
+	}
+	if h.barrier != nil {
+		h.barrier.WritePointer(*word, value)
+	}
+	*word = value
+}
+
+// AddGlobal adds global variable, globals are roots like stacks
+func (h *Heap) AddGlobal(addr uintptr) int {
+	h.globals = append(h.globals, addr)
+	return len(h.globals) - 1
+}
+
+func (h *Heap) SetGlobal(idx int, addr uintptr) {
+	if h.barrier != nil {
+		h.barrier.WritePointer(h.globals[idx], addr)
+	}
+	h.globals[idx] = addr
+}
+
+// children calls action for every non-nil pointer of the object
+func (h *Heap) children(object *Object, action func(uintptr)) {
+	offset := h.offset(object.Addr)
+	for pointers := object.Pointers; pointers != 0; pointers &= pointers - 1 {
+		if child := h.arena[offset+bits.TrailingZeros64(pointers)]; child != 0 {
+			action(child)
+		}
+	}
+}
+
+// roots returns objects referenced by stacks and globals, stacks are scanned
+// conservatively, so any word equal to the address of an object is a root
+func (h *Heap) roots(stacks [][]uintptr) []*Object {
+	var roots []*Object
+	for _, words := range append(slices.Clone(stacks), h.globals) {
+		for _, word := range words {
+			if object, ok := h.objects[word]; ok {
+				roots = append(roots, object)
+			}
+		}
+	}
+	return roots
+}
+
+// mark uses explicit stack instead of recursion, so long chains of objects
+// don't overflow goroutine stack, pointers to other heaps are skipped
+func (h *Heap) mark(roots []*Object) markBits {
+	marks := newMarkBits(len(h.arena))
+	pending := roots
+	for len(pending) > 0 {
+		object := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		offset := h.offset(object.Addr)
+		if marks.test(offset) {
+			continue
+		}
+
+		marks.set(offset)
+		h.children(object, func(child uintptr) {
+			if object, ok := h.objects[child]; ok {
+				pending = append(pending, object)
+			}
+		})
+	}
+	return marks
+}
+
+type CollectStats struct {
+	Reachable    []uintptr // sorted by address
+	FreedObjects int
+	FreedBytes   int
+	LiveBytes    int
+}
+
+// Collect runs full mark-and-sweep cycle
+func (h *Heap) Collect(stacks [][]uintptr) CollectStats {
+	return h.sweep(h.mark(h.roots(stacks)))
+}
+
+// sweep frees unmarked objects and rebuilds the free list from gaps
+// between live objects, so adjacent free spans are merged
+func (h *Heap) sweep(marks markBits) CollectStats {
+	var stats CollectStats
+	h.free = h.free[:0]
+
+	end := 0
+	for _, object := range h.sortedObjects() {
+		offset := h.offset(object.Addr)
+		if !marks.test(offset) {
+			clear(h.arena[offset : offset+object.Size])
+			delete(h.objects, object.Addr)
+			stats.FreedObjects++
+			stats.FreedBytes += object.Size * wordSize
+			continue
+		}
+
+		if offset > end {
+			h.free = append(h.free, span{offset: end, size: offset - end})
+		}
+		end = offset + object.Size
+
+		stats.Reachable = append(stats.Reachable, object.Addr)
+		stats.LiveBytes += object.Size * wordSize
+	}
+
+	if end < len(h.arena) {
+		h.free = append(h.free, span{offset: end, size: len(h.arena) - end})
+	}
+	return stats
+}
+
+func (h *Heap) sortedObjects() []*Object {
+	objects := make([]*Object, 0, len(h.objects))
+	for _, object := range h.objects {
+		objects = append(objects, object)
+	}
+	slices.SortFunc(objects, func(lhs, rhs *Object) int {
+		return int(lhs.Addr) - int(rhs.Addr)
+	})
+	return objects
+}
+
+func (h *Heap) LiveBytes() int {
+	var words int
+	for _, object := range h.objects {
+		words += object.Size
+	}
+	return words * wordSize
+}
+
+func (h *Heap) FreeBytes() int {
+	var words int
+	for _, free := range h.free {
+		words += free.size
+	}
+	return words * wordSize
+}
+
+func mustAllocate(t *testing.T, heap *Heap, size int, pointers uint64) uintptr {
+	addr, err := heap.Allocate(size, pointers)
+	require.NoError(t, err)
+	return addr
+}
+
+func TestHeapLikeTrace(t *testing.T) {
+	// every object is a single pointer like in TestTrace
+	heap := NewHeap(16)
+	objects := make([]uintptr, 8)
+	for idx := range objects {
+		objects[idx] = mustAllocate(t, heap, 1, 1)
+	}
+
+	heap.Store(objects[0], 0, objects[1])
+	heap.Store(objects[1], 0, objects[2])
+	heap.Store(objects[3], 0, objects[4])
+	heap.Store(objects[4], 0, objects[3])
+	heap.Store(objects[6], 0, objects[7])
+
+	stacks := [][]uintptr{
+		{objects[0], 0x00, 0x00, objects[3]},
+		{0x00, objects[5], 0x00, 0x00},
+	}
+
+	stats := heap.Collect(stacks)
+	assert.ElementsMatch(t, Trace(stacks), stats.Reachable)
+	assert.Equal(t, objects[:6], stats.Reachable)
+	assert.Equal(t, 2, stats.FreedObjects)
+	assert.Equal(t, 2*wordSize, stats.FreedBytes)
+	assert.Equal(t, 6*wordSize, stats.LiveBytes)
+}
+
+func TestHeapCollect(t *testing.T) {
+	// node is {value, left, right}
+	const nodeSize, nodePointers = 3, 0b110
+
+	heap := NewHeap(64)
+	root := mustAllocate(t, heap, nodeSize, nodePointers)
+	left := mustAllocate(t, heap, nodeSize, nodePointers)
+	right := mustAllocate(t, heap, nodeSize, nodePointers)
+	garbage := mustAllocate(t, heap, nodeSize, nodePointers)
+	cycle := mustAllocate(t, heap, nodeSize, nodePointers)
+	global := mustAllocate(t, heap, 2, 0)
+
+	heap.Store(root, 0, 42)
+	heap.Store(root, 1, left)
+	heap.Store(root, 2, right)
+	heap.Store(right, 1, root) // cycle with the root
+	heap.Store(garbage, 1, cycle)
+	heap.Store(cycle, 1, garbage) // unreachable cycle
+	heap.AddGlobal(global)
+
+	stats := heap.Collect([][]uintptr{{0x00, root}})
+	assert.Equal(t, []uintptr{root, left, right, global}, stats.Reachable)
+	assert.Equal(t, 2, stats.FreedObjects)
+	assert.Equal(t, (3*nodeSize+2)*wordSize, stats.LiveBytes)
+	assert.Equal(t, stats.LiveBytes, heap.LiveBytes())
+	assert.Equal(t, 64*wordSize-stats.LiveBytes, heap.FreeBytes())
+	assert.Equal(t, uintptr(42), heap.Load(root, 0))
+
+	_, ok := heap.Object(garbage)
+	assert.False(t, ok)
+	assert.Panics(t, func() { heap.Load(garbage, 0) })
+	assert.Panics(t, func() { heap.Store(root, 1, garbage) })
+
+	heap.SetGlobal(0, 0)
+	stats = heap.Collect(nil)
+	assert.Empty(t, stats.Reachable)
+	assert.Zero(t, heap.Len())
+	assert.Equal(t, 64*wordSize, heap.FreeBytes())
+}
+
+func TestHeapFreeList(t *testing.T) {
+	heap := NewHeap(8)
+	first := mustAllocate(t, heap, 2, 0)
+	second := mustAllocate(t, heap, 2, 0)
+	third := mustAllocate(t, heap, 2, 0)
+	fourth := mustAllocate(t, heap, 2, 0)
+
+	_, err := heap.Allocate(1, 0)
+	assert.ErrorIs(t, err, ErrOutOfMemory)
+
+	// freed spans of the first and the second objects are merged
+	heap.Collect([][]uintptr{{third}})
+	reused, err := heap.Allocate(4, 0)
+	require.NoError(t, err)
+	assert.Equal(t, first, reused)
+
+	// the fourth span is free as well
+	last, err := heap.Allocate(2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, fourth, last)
+
+	_, err = heap.Allocate(1, 0)
+	assert.ErrorIs(t, err, ErrOutOfMemory)
+	assert.NotEqual(t, second, third)
+}
+
+func TestHeapLongChain(t *testing.T) {
+	const length = 1_000_000
+
+	heap := NewHeap(length)
+	previous := uintptr(0)
+	for range length {
+		object := mustAllocate(t, heap, 1, 1)
+		heap.Store(object, 0, previous)
+		previous = object
+	}
+
+	stats := heap.Collect([][]uintptr{{previous}})
+	assert.Len(t, stats.Reachable, length)
+	assert.Zero(t, stats.FreedObjects)
+}
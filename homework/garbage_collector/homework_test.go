@@ -38,6 +38,10 @@ func Trace(stacks [][]uintptr) []uintptr {
 	return res
 }
 
+// traceObjects keeps objects of TestTrace on the heap, objects on the stack
+// are moved when the stack grows or is shrunk by GC, so uintptr becomes stale
+var traceObjects []any
+
 func TestTrace(t *testing.T) {
 	var heapObjects = []int{
 		0x00, 0x00, 0x00, 0x00, 0x00,
@@ -47,6 +51,7 @@ func TestTrace(t *testing.T) {
 	var heapPointer2 *int = &heapObjects[2]
 	var heapPointer3 *int = nil
 	var heapPointer4 **int = &heapPointer3
+	traceObjects = []any{&heapObjects, &heapPointer1, &heapPointer2, &heapPointer3, &heapPointer4}
 
 	var stacks = [][]uintptr{
 		{
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTrace converts uintptr to unsafe.Pointer and fails checkptr of the race detector
+// go test -v -race -run Mark homework_test.go heap_test.go parallel_mark_test.go
+// go test -bench=Mark -run=^$ homework_test.go heap_test.go parallel_mark_test.go
+
+type atomicMarkBits []atomic.Uint64
+
+func newAtomicMarkBits(words int) atomicMarkBits {
+	return make(atomicMarkBits, (words+63)/64)
+}
+
+// trySet reports whether the bit was set by this call
+func (m atomicMarkBits) trySet(offset int) bool {
+	mask := uint64(1) << (offset % 64)
+	return m[offset/64].Or(mask)&mask == 0
+}
+
+func (m atomicMarkBits) load() markBits {
+	marks := make(markBits, len(m))
+	for idx := range m {
+		marks[idx] = m[idx].Load()
+	}
+	return marks
+}
+
+// greyQueue is a deque, the owner works with its tail
+// and other workers steal from its head
+type greyQueue struct {
+	mutex   sync.Mutex
+	objects []*Object
+}
+
+func (q *greyQueue) push(object *Object) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.objects = append(q.objects, object)
+}
+
+func (q *greyQueue) pop() (*Object, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.objects) == 0 {
+		return nil, false
+	}
+
+	object := q.objects[len(q.objects)-1]
+	q.objects = q.objects[:len(q.objects)-1]
+	return object, true
+}
+
+// steal takes the older half of objects, they are usually
+// closer to roots, so they lead to larger parts of the graph
+func (q *greyQueue) steal() []*Object {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	count := (len(q.objects) + 1) / 2
+	stolen := append([]*Object(nil), q.objects[:count]...)
+	q.objects = append(q.objects[:0], q.objects[count:]...)
+	return stolen
+}
+
+type parallelMarker struct {
+	heap    *Heap
+	marks   atomicMarkBits
+	queues  []greyQueue
+	pending atomic.Int64 // grey objects which aren't scanned yet
+}
+
+func (m *parallelMarker) shade(worker int, addr uintptr) {
+	object := m.heap.objects[addr]
+	if m.marks.trySet(m.heap.offset(addr)) {
+		m.pending.Add(1)
+		m.queues[worker].push(object)
+	}
+}
+
+func (m *parallelMarker) next(worker int, random *rand.Rand) (*Object, bool) {
+	if object, ok := m.queues[worker].pop(); ok {
+		return object, true
+	}
+
+	for m.pending.Load() > 0 {
+		start := random.IntN(len(m.queues))
+		for idx := range m.queues {
+			victim := (start + idx) % len(m.queues)
+			if victim == worker {
+				continue
+			}
+
+			// stolen objects may be stolen back before pop,
+			// so the worker keeps looking for work in this case
+			if stealTo(&m.queues[victim], &m.queues[worker]) {
+				if object, ok := m.queues[worker].pop(); ok {
+					return object, true
+				}
+			}
+		}
+		runtime.Gosched()
+	}
+	return nil, false
+}
+
+func stealTo(victim, thief *greyQueue) bool {
+	stolen := victim.steal()
+	for _, object := range stolen {
+		thief.push(object)
+	}
+	return len(stolen) > 0
+}
+
+func (m *parallelMarker) run(worker int) {
+	random := rand.New(rand.NewPCG(uint64(worker), 0))
+	for {
+		object, ok := m.next(worker, random)
+		if !ok {
+			return
+		}
+
+		m.heap.children(object, func(child uintptr) {
+			m.shade(worker, child)
+		})
+		m.pending.Add(-1)
+	}
+}
+
+// parallelMark shards root stacks across workers, every worker marks
+// objects from its own queue and steals from others when it is empty
+func (h *Heap) parallelMark(stacks [][]uintptr, workers int) markBits {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	m := &parallelMarker{
+		heap:   h,
+		marks:  newAtomicMarkBits(len(h.arena)),
+		queues: make([]greyQueue, workers),
+	}
+	for idx, stack := range append(slices.Clone(stacks), h.globals) {
+		for _, root := range h.roots([][]uintptr{stack}) {
+			m.shade(idx%workers, root.Addr)
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for worker := range workers {
+		go func() {
+			defer wg.Done()
+			m.run(worker)
+		}()
+	}
+	wg.Wait()
+
+	return m.marks.load()
+}
+
+// ParallelMark returns reachable objects sorted by address
+func (h *Heap) ParallelMark(stacks [][]uintptr, workers int) []uintptr {
+	marks := h.parallelMark(stacks, workers)
+
+	var reachable []uintptr
+	for _, object := range h.sortedObjects() {
+		if marks.test(h.offset(object.Addr)) {
+			reachable = append(reachable, object.Addr)
+		}
+	}
+	return reachable
+}
+
+func (h *Heap) ParallelCollect(stacks [][]uintptr, workers int) CollectStats {
+	return h.sweep(h.parallelMark(stacks, workers))
+}
+
+// newRandomGraph builds objects with pointers to random objects,
+// some of the objects are unreachable
+func newRandomGraph(objects, size int, pointers uint64) (*Heap, [][]uintptr) {
+	random := rand.New(rand.NewPCG(uint64(objects), uint64(size)))
+
+	heap := NewHeap(objects * size)
+	addrs := make([]uintptr, objects)
+	for idx := range addrs {
+		addrs[idx], _ = heap.Allocate(size, pointers)
+	}
+
+	for _, addr := range addrs {
+		for field := range size {
+			if pointers&(1<<field) != 0 && random.IntN(4) != 0 {
+				heap.Store(addr, field, addrs[random.IntN(len(addrs))])
+			}
+		}
+	}
+
+	stacks := make([][]uintptr, 8)
+	for idx := range stacks {
+		stacks[idx] = make([]uintptr, 16)
+		for slot := range stacks[idx] {
+			if random.IntN(8) == 0 {
+				stacks[idx][slot] = addrs[random.IntN(len(addrs))]
+			}
+		}
+	}
+	return heap, stacks
+}
+
+func TestParallelMarkSinglePointers(t *testing.T) {
+	// every object is a single pointer like in TestTrace
+	heap, stacks := newRandomGraph(100_000, 1, 1)
+	marks := heap.mark(heap.roots(stacks))
+
+	var expected []uintptr
+	for _, object := range heap.sortedObjects() {
+		if marks.test(heap.offset(object.Addr)) {
+			expected = append(expected, object.Addr)
+		}
+	}
+	assert.NotEmpty(t, expected)
+	assert.Less(t, len(expected), heap.Len())
+
+	for _, workers := range []int{1, 2, 4, 8, 0} {
+		assert.Equal(t, expected, heap.ParallelMark(stacks, workers))
+	}
+}
+
+func TestParallelMark(t *testing.T) {
+	heap, stacks := newRandomGraph(50_000, 4, 0b1101)
+	expected := heap.mark(heap.roots(stacks))
+
+	for _, workers := range []int{1, 3, 8} {
+		assert.Equal(t, expected, heap.parallelMark(stacks, workers))
+	}
+
+	heap.AddGlobal(0)
+	assert.Empty(t, heap.ParallelMark(nil, 4))
+
+	stats := heap.ParallelCollect(stacks, 4)
+	assert.Equal(t, stats.Reachable, heap.ParallelMark(stacks, 4))
+	assert.Equal(t, stats.Reachable, heap.Collect(stacks).Reachable)
+}
+
+func BenchmarkMark(b *testing.B) {
+	heap, stacks := newRandomGraph(1_000_000, 4, 0b1101)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		heap.mark(heap.roots(stacks))
+	}
+}
+
+func BenchmarkParallelMark(b *testing.B) {
+	heap, stacks := newRandomGraph(1_000_000, 4, 0b1101)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		heap.parallelMark(stacks, 0)
+	}
+}
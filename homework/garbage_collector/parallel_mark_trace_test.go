@@ -0,0 +1,24 @@
+//go:build !race
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Trace converts uintptr to unsafe.Pointer and fails checkptr of the race detector
+// go test -v -run Trace homework_test.go heap_test.go parallel_mark_test.go parallel_mark_trace_test.go
+
+func TestParallelMarkLikeTrace(t *testing.T) {
+	// every object is a single pointer like in TestTrace
+	heap, stacks := newRandomGraph(100_000, 1, 1)
+	expected := Trace(stacks)
+	assert.NotEmpty(t, expected)
+	assert.Less(t, len(expected), heap.Len())
+
+	for _, workers := range []int{1, 2, 4, 8, 0} {
+		assert.ElementsMatch(t, expected, heap.ParallelMark(stacks, workers))
+	}
+}
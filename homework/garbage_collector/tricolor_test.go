@@ -0,0 +1,292 @@
+package main
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v homework_test.go heap_test.go tricolor_test.go
+
+type Color int
+
+const (
+	White Color = iota // not reached yet, swept at the end of the cycle
+	Grey               // reached, but pointers aren't scanned yet
+	Black              // reached and scanned
+)
+
+type BarrierKind int
+
+const (
+	NoBarrier BarrierKind = iota
+	// Dijkstra barrier shades the new pointer, so black object never points
+	// to white one, roots are rescanned when the marking is finished
+	Dijkstra
+	// Yuasa barrier shades the overwritten pointer, so everything reachable
+	// at the start of the cycle is marked (snapshot at the beginning)
+	Yuasa
+)
+
+// Marker marks the heap incrementally, steps may be interleaved with
+// mutations of the heap, which are tracked by the write barrier
+type Marker struct {
+	heap   *Heap
+	kind   BarrierKind
+	colors map[uintptr]Color
+	grey   []*Object // work queue, its objects are grey
+}
+
+// StartMarking shades roots and installs the write barrier,
+// the heap must not be collected until the marking is finished
+func (h *Heap) StartMarking(stacks [][]uintptr, kind BarrierKind) *Marker {
+	m := &Marker{heap: h, kind: kind, colors: make(map[uintptr]Color, len(h.objects))}
+	m.shadeRoots(stacks)
+	h.barrier = m
+	return m
+}
+
+func (m *Marker) shade(addr uintptr) {
+	if addr == 0 || m.colors[addr] != White {
+		return
+	}
+	m.colors[addr] = Grey
+	m.grey = append(m.grey, m.heap.objects[addr])
+}
+
+func (m *Marker) shadeRoots(stacks [][]uintptr) {
+	for _, root := range m.heap.roots(stacks) {
+		m.shade(root.Addr)
+	}
+}
+
+func (m *Marker) WritePointer(old, new uintptr) {
+	switch m.kind {
+	case Dijkstra:
+		m.shade(new)
+	case Yuasa:
+		m.shade(old)
+	}
+}
+
+// Allocated makes new objects black, they survive the current cycle
+func (m *Marker) Allocated(addr uintptr) {
+	m.colors[addr] = Black
+}
+
+func (m *Marker) Color(addr uintptr) Color {
+	return m.colors[addr]
+}
+
+// Step scans up to budget grey objects and reports
+// whether there are no grey objects anymore
+func (m *Marker) Step(budget int) bool {
+	for ; budget > 0 && len(m.grey) > 0; budget-- {
+		object := m.grey[len(m.grey)-1]
+		m.grey = m.grey[:len(m.grey)-1]
+
+		m.heap.children(object, m.shade)
+		m.colors[object.Addr] = Black
+	}
+	return len(m.grey) == 0
+}
+
+// Finish rescans roots, marks the rest of grey objects,
+// sweeps white objects and removes the write barrier
+func (m *Marker) Finish(stacks [][]uintptr) CollectStats {
+	m.shadeRoots(stacks)
+	for !m.Step(len(m.grey)) {
+	}
+
+	m.heap.barrier = nil
+	marks := newMarkBits(len(m.heap.arena))
+	for addr, color := range m.colors {
+		if color == Black {
+			marks.set(m.heap.offset(addr))
+		}
+	}
+	return m.heap.sweep(marks)
+}
+
+// node is {value, left, right}
+const nodeSize, nodePointers = 3, 0b110
+
+// mutator changes the heap like a program, so it uses only reachable objects
+type mutator struct {
+	t      *testing.T
+	heap   *Heap
+	stacks [][]uintptr
+	random *rand.Rand
+}
+
+func (m *mutator) reachable() []uintptr {
+	marks := m.heap.mark(m.heap.roots(m.stacks))
+
+	var reachable []uintptr
+	for _, object := range m.heap.sortedObjects() {
+		if marks.test(m.heap.offset(object.Addr)) {
+			reachable = append(reachable, object.Addr)
+		}
+	}
+	return reachable
+}
+
+func (m *mutator) pick(reachable []uintptr) uintptr {
+	if len(reachable) == 0 || m.random.IntN(5) == 0 {
+		return 0
+	}
+	return reachable[m.random.IntN(len(reachable))]
+}
+
+func (m *mutator) mutate() {
+	reachable := m.reachable()
+	stack := m.stacks[m.random.IntN(len(m.stacks))]
+
+	switch m.random.IntN(4) {
+	case 0:
+		node := mustAllocate(m.t, m.heap, nodeSize, nodePointers)
+		if target := m.pick(reachable); target != 0 {
+			m.heap.Store(target, 1+m.random.IntN(2), node)
+		} else {
+			stack[m.random.IntN(len(stack))] = node
+		}
+	case 1:
+		if target := m.pick(reachable); target != 0 {
+			m.heap.Store(target, 1+m.random.IntN(2), m.pick(reachable))
+		}
+	case 2:
+		stack[m.random.IntN(len(stack))] = m.pick(reachable)
+	case 3:
+		m.heap.SetGlobal(0, m.pick(reachable))
+	}
+}
+
+func newRandomHeap(t *testing.T, random *rand.Rand) (*Heap, [][]uintptr) {
+	heap := NewHeap(1 << 16)
+	nodes := make([]uintptr, 200)
+	for idx := range nodes {
+		nodes[idx] = mustAllocate(t, heap, nodeSize, nodePointers)
+	}
+	for _, node := range nodes {
+		heap.Store(node, 1, nodes[random.IntN(len(nodes))])
+		heap.Store(node, 2, nodes[random.IntN(len(nodes))])
+	}
+
+	heap.AddGlobal(nodes[0])
+	stacks := [][]uintptr{
+		{nodes[1], 0x00, nodes[2], 0x00},
+		{0x00, nodes[3], 0x00, 0x00},
+	}
+	return heap, stacks
+}
+
+func TestIncrementalMarking(t *testing.T) {
+	for _, kind := range []BarrierKind{Dijkstra, Yuasa} {
+		for seed := range uint64(20) {
+			random := rand.New(rand.NewPCG(seed, uint64(kind)))
+			heap, stacks := newRandomHeap(t, random)
+			mutator := &mutator{t: t, heap: heap, stacks: stacks, random: random}
+
+			marker := heap.StartMarking(stacks, kind)
+			for !marker.Step(2) {
+				for range 3 {
+					mutator.mutate()
+				}
+			}
+			for range 10 {
+				mutator.mutate()
+			}
+
+			reachable := mutator.reachable()
+			stats := marker.Finish(stacks)
+
+			for _, addr := range reachable {
+				_, ok := heap.Object(addr)
+				require.True(t, ok, "reachable object is swept: kind %d, seed %d", kind, seed)
+			}
+			assert.Subset(t, stats.Reachable, reachable)
+
+			// the next full cycle collects floating garbage of the incremental one
+			assert.Equal(t, reachable, heap.Collect(stacks).Reachable)
+		}
+	}
+}
+
+func TestWriteBarrier(t *testing.T) {
+	tests := map[string]struct {
+		kind BarrierKind
+		lost bool
+	}{
+		"without barrier": {kind: NoBarrier, lost: true},
+		"dijkstra":        {kind: Dijkstra},
+		"yuasa":           {kind: Yuasa},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			heap := NewHeap(64)
+			first := mustAllocate(t, heap, nodeSize, nodePointers)
+			second := mustAllocate(t, heap, nodeSize, nodePointers)
+			hidden := mustAllocate(t, heap, nodeSize, nodePointers)
+			heap.Store(second, 1, hidden)
+			stacks := [][]uintptr{{second, first}}
+
+			// first is scanned, second is still grey
+			marker := heap.StartMarking(stacks, test.kind)
+			marker.Step(1)
+			require.Equal(t, Black, marker.Color(first))
+			require.Equal(t, Grey, marker.Color(second))
+
+			// hidden is moved from grey object to black one
+			heap.Store(first, 1, hidden)
+			heap.Store(second, 1, 0)
+
+			marker.Finish(stacks)
+			_, ok := heap.Object(hidden)
+			assert.Equal(t, test.lost, !ok)
+		})
+	}
+}
+
+func TestIncrementalMarkingAllocation(t *testing.T) {
+	heap := NewHeap(64)
+	root := mustAllocate(t, heap, nodeSize, nodePointers)
+	stacks := [][]uintptr{{root}}
+
+	marker := heap.StartMarking(stacks, Yuasa)
+	require.True(t, marker.Step(10))
+
+	// new object is stored to black root, it is black as well
+	node := mustAllocate(t, heap, nodeSize, nodePointers)
+	heap.Store(root, 1, node)
+	assert.Equal(t, Black, marker.Color(node))
+
+	stats := marker.Finish(stacks)
+	assert.Equal(t, []uintptr{root, node}, stats.Reachable)
+	assert.Nil(t, heap.barrier)
+}
+
+func TestIncrementalMarkingLongChain(t *testing.T) {
+	const length = 100_000
+
+	heap := NewHeap(length)
+	previous := uintptr(0)
+	for range length {
+		object := mustAllocate(t, heap, 1, 1)
+		heap.Store(object, 0, previous)
+		previous = object
+	}
+
+	stacks := [][]uintptr{{previous}}
+	marker := heap.StartMarking(stacks, Dijkstra)
+	steps := 1
+	for ; !marker.Step(1000); steps++ {
+	}
+
+	assert.Equal(t, length/1000, steps)
+	assert.Len(t, marker.Finish(stacks).Reachable, length)
+	assert.True(t, slices.IsSorted(heap.Collect(stacks).Reachable))
+}
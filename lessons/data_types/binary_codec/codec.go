@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// Wire layout is the memory layout of the struct: every field is placed
+// at the same offset as in memory, so the struct must not contain any
+// implicit padding. Multi-byte fields must declare their byte order
+// with `bin:"be"` or `bin:"le"` tag, explicit padding may be declared
+// with blank byte array fields (`_ [2]byte`).
+
+var (
+	ErrInvalidLayout = errors.New("invalid binary layout")
+	ErrShortBuffer   = errors.New("short buffer")
+)
+
+type chunk struct {
+	offset uintptr
+	size   uintptr
+	width  uintptr // element width for byte swapping, 0 for plain copy
+}
+
+type Codec[T any] struct {
+	size   uintptr
+	chunks []chunk
+}
+
+func Register[T any]() (*Codec[T], error) {
+	var value T
+	typ := reflect.TypeOf(value)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %v is not a struct", ErrInvalidLayout, typ)
+	}
+
+	var codec Codec[T]
+	if err := codec.addStruct(typ, 0); err != nil {
+		return nil, err
+	}
+
+	if codec.size != typ.Size() {
+		return nil, fmt.Errorf("%w: %v has %d bytes of trailing padding",
+			ErrInvalidLayout, typ, typ.Size()-codec.size)
+	}
+
+	return &codec, nil
+}
+
+func MustRegister[T any]() *Codec[T] {
+	codec, err := Register[T]()
+	if err != nil {
+		panic(err)
+	}
+	return codec
+}
+
+func (c *Codec[T]) Size() int {
+	return int(c.size)
+}
+
+func (c *Codec[T]) Marshal(dst []byte, value *T) error {
+	if uintptr(len(dst)) < c.size {
+		return fmt.Errorf("%w: need %d bytes, got %d", ErrShortBuffer, c.size, len(dst))
+	}
+
+	base := unsafe.Pointer(value)
+	for _, ch := range c.chunks {
+		mem := unsafe.Slice((*byte)(unsafe.Add(base, ch.offset)), ch.size)
+		transfer(dst[ch.offset:ch.offset+ch.size], mem, ch.width)
+	}
+	return nil
+}
+
+func (c *Codec[T]) Append(dst []byte, value *T) []byte {
+	dst = append(dst, make([]byte, c.size)...)
+	_ = c.Marshal(dst[uintptr(len(dst))-c.size:], value)
+	return dst
+}
+
+func (c *Codec[T]) Unmarshal(src []byte, value *T) error {
+	if uintptr(len(src)) < c.size {
+		return fmt.Errorf("%w: need %d bytes, got %d", ErrShortBuffer, c.size, len(src))
+	}
+
+	base := unsafe.Pointer(value)
+	for _, ch := range c.chunks {
+		mem := unsafe.Slice((*byte)(unsafe.Add(base, ch.offset)), ch.size)
+		transfer(mem, src[ch.offset:ch.offset+ch.size], ch.width)
+	}
+	return nil
+}
+
+func transfer(dst, src []byte, width uintptr) {
+	if width == 0 {
+		copy(dst, src)
+		return
+	}
+
+	for offset := uintptr(0); offset < uintptr(len(src)); offset += width {
+		for idx := range width {
+			dst[offset+idx] = src[offset+width-idx-1]
+		}
+	}
+}
+
+func (c *Codec[T]) addStruct(typ reflect.Type, base uintptr) error {
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		offset := base + field.Offset
+		if offset != c.size {
+			return fmt.Errorf("%w: %d bytes of padding before field %s.%s",
+				ErrInvalidLayout, offset-c.size, typ, field.Name)
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			if err := c.addStruct(field.Type, offset); err != nil {
+				return err
+			}
+			continue
+		}
+
+		width, err := elementWidth(field.Type)
+		if err != nil {
+			return fmt.Errorf("%w: field %s.%s: %v", ErrInvalidLayout, typ, field.Name, err)
+		}
+
+		swap, err := needSwap(field.Tag.Get("bin"), width)
+		if err != nil {
+			return fmt.Errorf("%w: field %s.%s: %v", ErrInvalidLayout, typ, field.Name, err)
+		}
+
+		if !swap {
+			width = 0
+		}
+
+		c.addChunk(chunk{offset: offset, size: field.Type.Size(), width: width})
+	}
+
+	return nil
+}
+
+func (c *Codec[T]) addChunk(ch chunk) {
+	c.size = ch.offset + ch.size
+	if ch.size == 0 {
+		return
+	}
+
+	// adjacent fields without byte swapping are copied at once
+	if last := len(c.chunks) - 1; last >= 0 && ch.width == 0 && c.chunks[last].width == 0 {
+		c.chunks[last].size += ch.size
+		return
+	}
+
+	c.chunks = append(c.chunks, ch)
+}
+
+func elementWidth(typ reflect.Type) (uintptr, error) {
+	switch typ.Kind() {
+	case reflect.Int8, reflect.Uint8,
+		reflect.Int16, reflect.Uint16,
+		reflect.Int32, reflect.Uint32,
+		reflect.Int64, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return typ.Size(), nil
+
+	case reflect.Array:
+		return elementWidth(typ.Elem())
+
+	default:
+		return 0, fmt.Errorf("unsupported type %v", typ)
+	}
+}
+
+func needSwap(tag string, width uintptr) (bool, error) {
+	switch tag {
+	case "be":
+		return width > 1 && isLittleEndian(), nil
+	case "le":
+		return width > 1 && !isLittleEndian(), nil
+	case "":
+		if width > 1 {
+			return false, errors.New("byte order is not specified")
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("unknown byte order %q", tag)
+	}
+}
+
+func isLittleEndian() bool {
+	var number int16 = 0x0001
+	pointer := (*int8)(unsafe.Pointer(&number))
+	return *pointer == 1
+}
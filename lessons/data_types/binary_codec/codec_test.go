@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v -bench=. .
+
+type IPv4Header struct {
+	VersionIHL  uint8
+	TOS         uint8
+	TotalLength uint16 `bin:"be"`
+	ID          uint16 `bin:"be"`
+	Fragment    uint16 `bin:"be"`
+	TTL         uint8
+	Protocol    uint8
+	Checksum    uint16 `bin:"be"`
+	Source      [4]byte
+	Destination [4]byte
+}
+
+type Trailer struct {
+	Flags    uint8
+	_        [3]byte
+	Sequence uint32 `bin:"le"`
+}
+
+type Frame struct {
+	Magic    uint32    `bin:"be"`
+	Counters [2]uint16 `bin:"le"`
+	Trailer  Trailer
+	Ratio    float64 `bin:"be"`
+}
+
+var headerBytes = []byte{
+	0x45, 0x00, 0x00, 0x3c,
+	0x1c, 0x46, 0x40, 0x00,
+	0x40, 0x06, 0xb1, 0xe6,
+	0xac, 0x10, 0x0a, 0x63,
+	0xac, 0x10, 0x0a, 0x0c,
+}
+
+func TestUnmarshalHeader(t *testing.T) {
+	codec, err := Register[IPv4Header]()
+	require.NoError(t, err)
+	assert.Equal(t, len(headerBytes), codec.Size())
+
+	var header IPv4Header
+	require.NoError(t, codec.Unmarshal(headerBytes, &header))
+
+	assert.Equal(t, IPv4Header{
+		VersionIHL:  0x45,
+		TotalLength: 60,
+		ID:          0x1c46,
+		Fragment:    0x4000,
+		TTL:         64,
+		Protocol:    6,
+		Checksum:    0xb1e6,
+		Source:      [4]byte{172, 16, 10, 99},
+		Destination: [4]byte{172, 16, 10, 12},
+	}, header)
+}
+
+func TestMarshalHeader(t *testing.T) {
+	codec := MustRegister[IPv4Header]()
+
+	var header IPv4Header
+	require.NoError(t, codec.Unmarshal(headerBytes, &header))
+
+	buffer := make([]byte, codec.Size())
+	require.NoError(t, codec.Marshal(buffer, &header))
+	assert.Equal(t, headerBytes, buffer)
+
+	assert.Equal(t, append([]byte{0xFF}, headerBytes...), codec.Append([]byte{0xFF}, &header))
+}
+
+func TestMixedByteOrder(t *testing.T) {
+	codec := MustRegister[Frame]()
+
+	frame := Frame{
+		Magic:    0x01020304,
+		Counters: [2]uint16{0x0506, 0x0708},
+		Trailer:  Trailer{Flags: 0x09, Sequence: 0x0A0B0C0D},
+		Ratio:    1.5,
+	}
+
+	var expected bytes.Buffer
+	_ = binary.Write(&expected, binary.BigEndian, frame.Magic)
+	_ = binary.Write(&expected, binary.LittleEndian, frame.Counters)
+	_ = binary.Write(&expected, binary.LittleEndian, [4]byte{frame.Trailer.Flags})
+	_ = binary.Write(&expected, binary.LittleEndian, frame.Trailer.Sequence)
+	_ = binary.Write(&expected, binary.BigEndian, frame.Ratio)
+
+	buffer := make([]byte, codec.Size())
+	require.NoError(t, codec.Marshal(buffer, &frame))
+	assert.Equal(t, expected.Bytes(), buffer)
+
+	var decoded Frame
+	require.NoError(t, codec.Unmarshal(buffer, &decoded))
+	assert.Equal(t, frame, decoded)
+}
+
+func TestShortBuffer(t *testing.T) {
+	codec := MustRegister[IPv4Header]()
+
+	var header IPv4Header
+	assert.ErrorIs(t, codec.Unmarshal(headerBytes[:10], &header), ErrShortBuffer)
+	assert.ErrorIs(t, codec.Marshal(make([]byte, 10), &header), ErrShortBuffer)
+}
+
+func TestInvalidLayout(t *testing.T) {
+	tests := map[string]func() error{
+		"not a struct": func() error {
+			_, err := Register[uint32]()
+			return err
+		},
+		"implicit padding": func() error {
+			_, err := Register[struct {
+				Flags  uint8
+				Length uint32 `bin:"be"`
+			}]()
+			return err
+		},
+		"trailing padding": func() error {
+			_, err := Register[struct {
+				Length uint32 `bin:"be"`
+				Flags  uint8
+			}]()
+			return err
+		},
+		"padding inside nested struct": func() error {
+			_, err := Register[struct {
+				Nested struct {
+					Length uint16 `bin:"be"`
+					Flags  uint8
+				}
+				Checksum uint16 `bin:"be"`
+			}]()
+			return err
+		},
+		"missing byte order": func() error {
+			_, err := Register[struct {
+				Length uint16
+			}]()
+			return err
+		},
+		"unknown byte order": func() error {
+			_, err := Register[struct {
+				Length uint16 `bin:"network"`
+			}]()
+			return err
+		},
+		"platform dependent size": func() error {
+			_, err := Register[struct {
+				Length int `bin:"be"`
+			}]()
+			return err
+		},
+		"unsupported type": func() error {
+			_, err := Register[struct {
+				Name string
+			}]()
+			return err
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, test(), ErrInvalidLayout)
+		})
+	}
+}
+
+func TestWithoutAllocations(t *testing.T) {
+	codec := MustRegister[Frame]()
+	buffer := make([]byte, codec.Size())
+
+	var frame Frame
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = codec.Marshal(buffer, &frame)
+		_ = codec.Unmarshal(buffer, &frame)
+	})
+	assert.Zero(t, allocs)
+}
+
+var header IPv4Header
+
+func BenchmarkCodecUnmarshal(b *testing.B) {
+	codec := MustRegister[IPv4Header]()
+	for i := 0; i < b.N; i++ {
+		_ = codec.Unmarshal(headerBytes, &header)
+	}
+}
+
+func BenchmarkBinaryRead(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = binary.Read(bytes.NewReader(headerBytes), binary.BigEndian, &header)
+	}
+}
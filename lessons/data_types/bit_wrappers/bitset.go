@@ -0,0 +1,212 @@
+package main
+
+import (
+	"iter"
+	"math/bits"
+)
+
+const wordSize = 64
+
+// BitSet grows automatically on Set, Flip and range operations,
+// bits outside of the allocated words are treated as cleared.
+type BitSet struct {
+	words []uint64
+}
+
+func NewBitSet(size int) *BitSet {
+	checkIndex(size)
+	return &BitSet{
+		words: make([]uint64, 0, wordsCount(size)),
+	}
+}
+
+func (b *BitSet) Set(index int) {
+	checkIndex(index)
+	b.grow(index + 1)
+	b.words[index/wordSize] |= 1 << (index % wordSize)
+}
+
+func (b *BitSet) Clear(index int) {
+	checkIndex(index)
+	if idx := index / wordSize; idx < len(b.words) {
+		b.words[idx] &^= 1 << (index % wordSize)
+	}
+}
+
+func (b *BitSet) Flip(index int) {
+	checkIndex(index)
+	b.grow(index + 1)
+	b.words[index/wordSize] ^= 1 << (index % wordSize)
+}
+
+func (b *BitSet) Test(index int) bool {
+	if index < 0 {
+		return false
+	}
+	if idx := index / wordSize; idx < len(b.words) {
+		return b.words[idx]&(1<<(index%wordSize)) != 0
+	}
+	return false
+}
+
+// SetRange, ClearRange and FlipRange work with half-open range [from, to).
+
+func (b *BitSet) SetRange(from, to int) {
+	checkRange(from, to)
+	b.grow(to)
+	b.applyRange(from, to, func(word, mask uint64) uint64 { return word | mask })
+}
+
+func (b *BitSet) ClearRange(from, to int) {
+	checkRange(from, to)
+	to = min(to, len(b.words)*wordSize)
+	b.applyRange(from, to, func(word, mask uint64) uint64 { return word &^ mask })
+}
+
+func (b *BitSet) FlipRange(from, to int) {
+	checkRange(from, to)
+	b.grow(to)
+	b.applyRange(from, to, func(word, mask uint64) uint64 { return word ^ mask })
+}
+
+func (b *BitSet) applyRange(from, to int, action func(word, mask uint64) uint64) {
+	for from < to {
+		idx := from / wordSize
+		offset := from % wordSize
+		count := min(wordSize-offset, to-from)
+
+		mask := ^uint64(0) >> (wordSize - count) << offset
+		b.words[idx] = action(b.words[idx], mask)
+		from += count
+	}
+}
+
+func (b *BitSet) And(other *BitSet) {
+	for idx := range b.words {
+		if idx < len(other.words) {
+			b.words[idx] &= other.words[idx]
+		} else {
+			b.words[idx] = 0
+		}
+	}
+}
+
+func (b *BitSet) Or(other *BitSet) {
+	b.grow(len(other.words) * wordSize)
+	for idx, word := range other.words {
+		b.words[idx] |= word
+	}
+}
+
+func (b *BitSet) Xor(other *BitSet) {
+	b.grow(len(other.words) * wordSize)
+	for idx, word := range other.words {
+		b.words[idx] ^= word
+	}
+}
+
+func (b *BitSet) AndNot(other *BitSet) {
+	for idx := range min(len(b.words), len(other.words)) {
+		b.words[idx] &^= other.words[idx]
+	}
+}
+
+func (b *BitSet) Count() int {
+	var count int
+	for _, word := range b.words {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+func (b *BitSet) Empty() bool {
+	for _, word := range b.words {
+		if word != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// NextSet returns the index of the first set bit starting from index (inclusive).
+func (b *BitSet) NextSet(index int) (int, bool) {
+	index = max(index, 0)
+	idx := index / wordSize
+	if idx >= len(b.words) {
+		return 0, false
+	}
+
+	word := b.words[idx] >> (index % wordSize)
+	if word != 0 {
+		return index + bits.TrailingZeros64(word), true
+	}
+
+	for idx++; idx < len(b.words); idx++ {
+		if b.words[idx] != 0 {
+			return idx*wordSize + bits.TrailingZeros64(b.words[idx]), true
+		}
+	}
+
+	return 0, false
+}
+
+func (b *BitSet) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for idx, word := range b.words {
+			for word != 0 {
+				offset := bits.TrailingZeros64(word)
+				if !yield(idx*wordSize + offset) {
+					return
+				}
+				word &= word - 1
+			}
+		}
+	}
+}
+
+func (b *BitSet) Equal(other *BitSet) bool {
+	longest, shortest := b.words, other.words
+	if len(longest) < len(shortest) {
+		longest, shortest = shortest, longest
+	}
+
+	for idx, word := range longest {
+		if idx < len(shortest) {
+			if word != shortest[idx] {
+				return false
+			}
+		} else if word != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (b *BitSet) Clone() *BitSet {
+	words := make([]uint64, len(b.words))
+	copy(words, b.words)
+	return &BitSet{words: words}
+}
+
+func (b *BitSet) grow(size int) {
+	if count := wordsCount(size); count > len(b.words) {
+		b.words = append(b.words, make([]uint64, count-len(b.words))...)
+	}
+}
+
+func wordsCount(size int) int {
+	return (size + wordSize - 1) / wordSize
+}
+
+func checkIndex(index int) {
+	if index < 0 {
+		panic("bitset: negative index")
+	}
+}
+
+func checkRange(from, to int) {
+	if from < 0 || from > to {
+		panic("bitset: invalid range")
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v .
+
+func bitSetOf(indexes ...int) *BitSet {
+	var set BitSet
+	for _, index := range indexes {
+		set.Set(index)
+	}
+	return &set
+}
+
+func TestBitSetOperations(t *testing.T) {
+	set := NewBitSet(10)
+	assert.True(t, set.Empty())
+
+	set.Set(0)
+	set.Set(63)
+	set.Set(64)
+	set.Set(200)
+	assert.True(t, set.Test(0))
+	assert.True(t, set.Test(63))
+	assert.True(t, set.Test(64))
+	assert.True(t, set.Test(200))
+	assert.False(t, set.Test(1))
+	assert.False(t, set.Test(1000))
+	assert.False(t, set.Test(-1))
+	assert.Equal(t, 4, set.Count())
+
+	set.Clear(63)
+	set.Clear(1000)
+	assert.False(t, set.Test(63))
+
+	set.Flip(0)
+	set.Flip(1)
+	assert.False(t, set.Test(0))
+	assert.True(t, set.Test(1))
+	assert.Equal(t, []int{1, 64, 200}, slices.Collect(set.All()))
+
+	assert.Panics(t, func() { set.Set(-1) })
+}
+
+func TestBitSetRanges(t *testing.T) {
+	tests := map[string]struct {
+		initial []int
+		action  func(*BitSet)
+		result  []int
+	}{
+		"set inside word": {
+			action: func(set *BitSet) { set.SetRange(3, 6) },
+			result: []int{3, 4, 5},
+		},
+		"set across words": {
+			action: func(set *BitSet) { set.SetRange(62, 66) },
+			result: []int{62, 63, 64, 65},
+		},
+		"set empty range": {
+			action: func(set *BitSet) { set.SetRange(5, 5) },
+		},
+		"clear across words": {
+			initial: []int{0, 62, 63, 64, 65, 130},
+			action:  func(set *BitSet) { set.ClearRange(63, 200) },
+			result:  []int{0, 62},
+		},
+		"flip across words": {
+			initial: []int{63, 65},
+			action:  func(set *BitSet) { set.FlipRange(62, 67) },
+			result:  []int{62, 64, 66},
+		},
+		"set whole words": {
+			action: func(set *BitSet) { set.SetRange(64, 192) },
+			result: func() []int {
+				var result []int
+				for i := 64; i < 192; i++ {
+					result = append(result, i)
+				}
+				return result
+			}(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			set := bitSetOf(test.initial...)
+			test.action(set)
+			assert.Equal(t, test.result, slices.Collect(set.All()))
+		})
+	}
+}
+
+func TestBitSetLogic(t *testing.T) {
+	tests := map[string]struct {
+		lhs    []int
+		rhs    []int
+		action func(lhs, rhs *BitSet)
+		result []int
+	}{
+		"and": {
+			lhs:    []int{1, 2, 100, 300},
+			rhs:    []int{2, 100, 101},
+			action: (*BitSet).And,
+			result: []int{2, 100},
+		},
+		"or": {
+			lhs:    []int{1, 2},
+			rhs:    []int{2, 100, 300},
+			action: (*BitSet).Or,
+			result: []int{1, 2, 100, 300},
+		},
+		"xor": {
+			lhs:    []int{1, 2, 100},
+			rhs:    []int{2, 100, 300},
+			action: (*BitSet).Xor,
+			result: []int{1, 300},
+		},
+		"and not": {
+			lhs:    []int{1, 2, 100, 300},
+			rhs:    []int{2, 300},
+			action: (*BitSet).AndNot,
+			result: []int{1, 100},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lhs, rhs := bitSetOf(test.lhs...), bitSetOf(test.rhs...)
+			test.action(lhs, rhs)
+			assert.Equal(t, test.result, slices.Collect(lhs.All()))
+			assert.True(t, lhs.Equal(bitSetOf(test.result...)))
+		})
+	}
+}
+
+func TestBitSetNextSet(t *testing.T) {
+	set := bitSetOf(3, 64, 130)
+
+	var found []int
+	for index, ok := set.NextSet(0); ok; index, ok = set.NextSet(index + 1) {
+		found = append(found, index)
+	}
+	assert.Equal(t, []int{3, 64, 130}, found)
+
+	index, ok := set.NextSet(65)
+	assert.True(t, ok)
+	assert.Equal(t, 130, index)
+
+	_, ok = set.NextSet(131)
+	assert.False(t, ok)
+}
+
+func TestBitSetIterationStop(t *testing.T) {
+	set := bitSetOf(1, 2, 3, 4)
+
+	var found []int
+	for index := range set.All() {
+		if index == 3 {
+			break
+		}
+		found = append(found, index)
+	}
+	assert.Equal(t, []int{1, 2}, found)
+}
+
+func TestBitSetEqualAndClone(t *testing.T) {
+	set := bitSetOf(1, 500)
+	clone := set.Clone()
+	assert.True(t, set.Equal(clone))
+
+	clone.Clear(500)
+	assert.False(t, set.Equal(clone))
+	assert.True(t, clone.Equal(bitSetOf(1)))
+	assert.True(t, bitSetOf(1).Equal(clone))
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// Column-oriented bitmap index: one compressed bitmap with row numbers
+// per attribute instead of one attributes mask per row.
+
+var indexMagic = [4]byte{'B', 'I', 'D', 'X'}
+
+var ErrCorruptedIndex = errors.New("corrupted bitmap index")
+
+type Index struct {
+	rows    uint32
+	columns map[int]*Bitmap
+}
+
+func NewIndex() *Index {
+	return &Index{
+		columns: make(map[int]*Bitmap),
+	}
+}
+
+func (i *Index) Add(attributes ...int) uint32 {
+	row := i.rows
+	for _, attribute := range attributes {
+		i.column(attribute).Add(row)
+	}
+	i.rows++
+	return row
+}
+
+func (i *Index) Rows() int {
+	return int(i.rows)
+}
+
+func (i *Index) Search(query Query) []uint32 {
+	return slices.Collect(query.evaluate(i).All())
+}
+
+func (i *Index) Count(query Query) int {
+	return query.evaluate(i).Cardinality()
+}
+
+func (i *Index) WriteTo(w io.Writer) (int64, error) {
+	writer := bufio.NewWriter(w)
+	counter := &countingWriter{w: writer}
+
+	header := struct {
+		Magic   [4]byte
+		Rows    uint32
+		Columns uint32
+	}{indexMagic, i.rows, uint32(len(i.columns))}
+
+	if err := binary.Write(counter, binary.LittleEndian, header); err != nil {
+		return counter.n, err
+	}
+
+	for _, attribute := range slices.Sorted(maps.Keys(i.columns)) {
+		if err := binary.Write(counter, binary.LittleEndian, int64(attribute)); err != nil {
+			return counter.n, err
+		}
+		if _, err := i.columns[attribute].WriteTo(counter); err != nil {
+			return counter.n, err
+		}
+	}
+
+	return counter.n, writer.Flush()
+}
+
+// ReadFrom reads the index without buffering, so data
+// after the index stays in the reader for the caller
+func (i *Index) ReadFrom(r io.Reader) (int64, error) {
+	counter := &countingReader{r: r}
+
+	var header struct {
+		Magic   [4]byte
+		Rows    uint32
+		Columns uint32
+	}
+	if err := binary.Read(counter, binary.LittleEndian, &header); err != nil {
+		return counter.n, err
+	}
+	if header.Magic != indexMagic {
+		return counter.n, ErrCorruptedIndex
+	}
+
+	columns := make(map[int]*Bitmap)
+	for range header.Columns {
+		var attribute int64
+		if err := binary.Read(counter, binary.LittleEndian, &attribute); err != nil {
+			return counter.n, err
+		}
+
+		bitmap := &Bitmap{}
+		if _, err := bitmap.ReadFrom(counter); err != nil {
+			return counter.n, fmt.Errorf("attribute %d: %w", attribute, err)
+		}
+		columns[int(attribute)] = bitmap
+	}
+
+	i.rows, i.columns = header.Rows, columns
+	return counter.n, nil
+}
+
+func (i *Index) column(attribute int) *Bitmap {
+	bitmap, ok := i.columns[attribute]
+	if !ok {
+		bitmap = NewBitmap()
+		i.columns[attribute] = bitmap
+	}
+	return bitmap
+}
+
+type Query interface {
+	evaluate(index *Index) *Bitmap
+}
+
+type attrQuery int
+
+type andQuery []Query
+
+type orQuery []Query
+
+type notQuery struct {
+	query Query
+}
+
+func Attr(attribute int) Query {
+	return attrQuery(attribute)
+}
+
+func And(queries ...Query) Query {
+	return andQuery(queries)
+}
+
+func Or(queries ...Query) Query {
+	return orQuery(queries)
+}
+
+func Not(query Query) Query {
+	return notQuery{query: query}
+}
+
+func (q attrQuery) evaluate(index *Index) *Bitmap {
+	if bitmap, ok := index.columns[int(q)]; ok {
+		return bitmap
+	}
+	return NewBitmap()
+}
+
+func (q andQuery) evaluate(index *Index) *Bitmap {
+	if len(q) == 0 {
+		return newRangeBitmap(index.rows)
+	}
+
+	result := q[0].evaluate(index)
+	for _, query := range q[1:] {
+		if notQuery, ok := query.(notQuery); ok {
+			result = result.AndNot(notQuery.query.evaluate(index))
+		} else {
+			result = result.And(query.evaluate(index))
+		}
+	}
+	return result
+}
+
+func (q orQuery) evaluate(index *Index) *Bitmap {
+	result := NewBitmap()
+	for _, query := range q {
+		result = result.Or(query.evaluate(index))
+	}
+	return result
+}
+
+func (q notQuery) evaluate(index *Index) *Bitmap {
+	return newRangeBitmap(index.rows).AndNot(q.query.evaluate(index))
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v -bench=. .
+
+const (
+	Hookah = iota
+	Pets
+	Veranda
+	Alcohol
+	LiveMusic
+)
+
+func restaurantsIndex() *Index {
+	index := NewIndex()
+	index.Add(Hookah, Veranda, Alcohol)
+	index.Add(Pets)
+	index.Add(LiveMusic)
+	index.Add(Hookah, Pets, Veranda, Alcohol, LiveMusic)
+	index.Add(Hookah, Alcohol)
+	return index
+}
+
+func TestIndexSearch(t *testing.T) {
+	tests := map[string]struct {
+		query  Query
+		result []uint32
+	}{
+		"single attribute": {
+			query:  Attr(Alcohol),
+			result: []uint32{0, 3, 4},
+		},
+		"and": {
+			query:  And(Attr(Hookah), Attr(Veranda)),
+			result: []uint32{0, 3},
+		},
+		"or": {
+			query:  Or(Attr(Pets), Attr(LiveMusic)),
+			result: []uint32{1, 2, 3},
+		},
+		"not": {
+			query:  Not(Attr(Alcohol)),
+			result: []uint32{1, 2},
+		},
+		"and not": {
+			query:  And(Attr(Alcohol), Not(Attr(LiveMusic))),
+			result: []uint32{0, 4},
+		},
+		"nested": {
+			query:  Or(And(Attr(Hookah), Not(Attr(Veranda))), Attr(Pets)),
+			result: []uint32{1, 3, 4},
+		},
+		"unknown attribute": {
+			query: Attr(100),
+		},
+		"empty and": {
+			query:  And(),
+			result: []uint32{0, 1, 2, 3, 4},
+		},
+	}
+
+	index := restaurantsIndex()
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := index.Search(test.query)
+			assert.Equal(t, test.result, result)
+			assert.Equal(t, len(test.result), index.Count(test.query))
+		})
+	}
+}
+
+func TestIndexMatchesRowScan(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	rows := make([]int8, 100_000)
+	for idx := range rows {
+		rows[idx] = int8(random.Intn(1 << 5))
+	}
+
+	index := indexFromRows(rows)
+	for pattern := range int8(1 << 5) {
+		expected := searchRestaurants(pattern, rows)
+
+		var result []int
+		for _, row := range index.Search(exactQuery(pattern, 5)) {
+			result = append(result, int(row))
+		}
+		assert.Equal(t, expected, result)
+	}
+}
+
+func TestIndexSerialization(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	index := NewIndex()
+	for range 200_000 {
+		// dense first attribute produces bitmap containers, others stay sparse
+		attributes := []int{random.Intn(64) + 1}
+		if random.Intn(2) == 0 {
+			attributes = append(attributes, 0)
+		}
+		index.Add(attributes...)
+	}
+
+	path := filepath.Join(t.TempDir(), "index.bin")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	_, err = index.WriteTo(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	file, err = os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	restored := NewIndex()
+	_, err = restored.ReadFrom(file)
+	require.NoError(t, err)
+
+	assert.Equal(t, index.Rows(), restored.Rows())
+	for attribute, column := range index.columns {
+		assert.True(t, column.Equal(restored.columns[attribute]), "attribute %d", attribute)
+	}
+
+	query := And(Attr(0), Not(Or(Attr(1), Attr(2))))
+	assert.Equal(t, index.Search(query), restored.Search(query))
+}
+
+func TestIndexCorruptedData(t *testing.T) {
+	var buffer bytes.Buffer
+	_, err := restaurantsIndex().WriteTo(&buffer)
+	require.NoError(t, err)
+
+	data := buffer.Bytes()
+	data[0] = 'X'
+	_, err = NewIndex().ReadFrom(bytes.NewReader(data))
+	assert.ErrorIs(t, err, ErrCorruptedIndex)
+
+	_, err = NewIndex().ReadFrom(bytes.NewReader(buffer.Bytes()[:20]))
+	assert.Error(t, err)
+}
+
+func TestIndexReadFromStream(t *testing.T) {
+	var buffer bytes.Buffer
+	_, err := restaurantsIndex().WriteTo(&buffer)
+	require.NoError(t, err)
+	size := buffer.Len()
+	buffer.WriteString("next record")
+
+	restored := NewIndex()
+	n, err := restored.ReadFrom(&buffer)
+	require.NoError(t, err)
+	assert.Equal(t, int64(size), n)
+	assert.Equal(t, "next record", buffer.String())
+	assert.Equal(t, []uint32{0, 3, 4}, restored.Search(Attr(Alcohol)))
+}
+
+func TestBitmapCorruptedData(t *testing.T) {
+	dense := make([]uint64, bitmapWords)
+	for idx := range arrayMaxSize/64 + 1 {
+		dense[idx] = ^uint64(0)
+	}
+
+	tests := map[string]struct {
+		kind  uint8
+		count uint32
+		data  any
+	}{
+		"unsorted array":    {kind: arrayContainer, count: 3, data: []uint16{1, 3, 2}},
+		"duplicates":        {kind: arrayContainer, count: 3, data: []uint16{1, 2, 2}},
+		"empty array":       {kind: arrayContainer, count: 0, data: []uint16{}},
+		"too large array":   {kind: arrayContainer, count: arrayMaxSize + 1, data: make([]uint16, arrayMaxSize+1)},
+		"sparse bitmap":     {kind: bitmapContainer, count: 1, data: append([]uint64{1}, make([]uint64, bitmapWords-1)...)},
+		"wrong count":       {kind: bitmapContainer, count: arrayMaxSize + 1, data: dense},
+		"unknown container": {kind: 2, count: 1, data: []uint16{1}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			header := struct {
+				Size  uint32
+				Key   uint16
+				Kind  uint8
+				Count uint32
+			}{1, 0, test.kind, test.count}
+			require.NoError(t, binary.Write(&buffer, binary.LittleEndian, header))
+			require.NoError(t, binary.Write(&buffer, binary.LittleEndian, test.data))
+
+			_, err := (&Bitmap{}).ReadFrom(&buffer)
+			assert.ErrorIs(t, err, ErrCorruptedBitmap)
+		})
+	}
+}
+
+func TestBitmapOperations(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	randomSet := func(count int, limit int32) map[uint32]struct{} {
+		set := make(map[uint32]struct{}, count)
+		for range count {
+			set[uint32(random.Int31n(limit))] = struct{}{}
+		}
+		return set
+	}
+
+	tests := map[string]struct {
+		lhs, rhs map[uint32]struct{}
+	}{
+		"sparse and sparse": {lhs: randomSet(1000, 1<<20), rhs: randomSet(1000, 1<<20)},
+		"dense and sparse":  {lhs: randomSet(50_000, 1<<17), rhs: randomSet(3000, 1<<17)},
+		"dense and dense":   {lhs: randomSet(50_000, 1<<17), rhs: randomSet(50_000, 1<<17)},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lhs, rhs := bitmapFromSet(test.lhs), bitmapFromSet(test.rhs)
+			assert.Equal(t, len(test.lhs), lhs.Cardinality())
+
+			var and, or, andNot []uint32
+			for value := range test.lhs {
+				if _, ok := test.rhs[value]; ok {
+					and = append(and, value)
+				} else {
+					andNot = append(andNot, value)
+				}
+				or = append(or, value)
+			}
+			for value := range test.rhs {
+				if _, ok := test.lhs[value]; !ok {
+					or = append(or, value)
+				}
+			}
+
+			slices.Sort(and)
+			slices.Sort(or)
+			slices.Sort(andNot)
+
+			assert.Equal(t, and, slices.Collect(lhs.And(rhs).All()))
+			assert.Equal(t, or, slices.Collect(lhs.Or(rhs).All()))
+			assert.Equal(t, andNot, slices.Collect(lhs.AndNot(rhs).All()))
+			assert.Equal(t, len(and), lhs.And(rhs).Cardinality())
+
+			for value := range test.rhs {
+				_, ok := test.lhs[value]
+				assert.Equal(t, ok, lhs.Contains(value))
+			}
+		})
+	}
+}
+
+func TestBitmapContainers(t *testing.T) {
+	bitmap := NewBitmap()
+	for value := range uint32(arrayMaxSize + 1) {
+		bitmap.Add(value * 2)
+	}
+	require.Len(t, bitmap.containers, 1)
+	assert.NotNil(t, bitmap.containers[0].bitmap)
+
+	sparse := bitmap.And(NewBitmap(2, 4, 5))
+	require.Len(t, sparse.containers, 1)
+	assert.Nil(t, sparse.containers[0].bitmap)
+	assert.Equal(t, []uint32{2, 4}, slices.Collect(sparse.All()))
+
+	assert.Equal(t, 100_000, newRangeBitmap(100_000).Cardinality())
+	assert.Empty(t, newRangeBitmap(0).containers)
+}
+
+func bitmapFromSet(set map[uint32]struct{}) *Bitmap {
+	bitmap := NewBitmap()
+	for value := range set {
+		bitmap.Add(value)
+	}
+	return bitmap
+}
+
+func indexFromRows(rows []int8) *Index {
+	index := NewIndex()
+	for _, row := range rows {
+		var attributes []int
+		for attribute := range 8 {
+			if row&(1<<attribute) != 0 {
+				attributes = append(attributes, attribute)
+			}
+		}
+		index.Add(attributes...)
+	}
+	return index
+}
+
+// exactQuery matches rows with exactly the same attributes like searchRestaurants
+func exactQuery(pattern int8, attributes int) Query {
+	queries := make([]Query, 0, attributes)
+	for attribute := range attributes {
+		if pattern&(1<<attribute) != 0 {
+			queries = append(queries, Attr(attribute))
+		} else {
+			queries = append(queries, Not(Attr(attribute)))
+		}
+	}
+	return And(queries...)
+}
+
+var indexes []int
+var rowsCount int
+
+func benchmarkRows() []int8 {
+	random := rand.New(rand.NewSource(42))
+	rows := make([]int8, 1_000_000)
+	for idx := range rows {
+		rows[idx] = int8(random.Intn(1 << 5))
+	}
+	return rows
+}
+
+func BenchmarkRowScan(b *testing.B) {
+	rows := benchmarkRows()
+	pattern := int8(0b00011000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		indexes = searchRestaurants(pattern, rows)
+	}
+}
+
+func BenchmarkBitmapIndexSearch(b *testing.B) {
+	index := indexFromRows(benchmarkRows())
+	query := exactQuery(0b00011000, 5)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rowsCount = len(index.Search(query))
+	}
+}
+
+func BenchmarkBitmapIndexCount(b *testing.B) {
+	index := indexFromRows(benchmarkRows())
+	query := And(Attr(Alcohol), Attr(LiveMusic))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rowsCount = index.Count(query)
+	}
+}
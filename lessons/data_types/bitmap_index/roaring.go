@@ -0,0 +1,453 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"iter"
+	"math/bits"
+	"slices"
+)
+
+// Roaring-style compressed bitmap: values are split by the high 16 bits
+// into containers, sparse containers keep sorted low 16 bits in the array,
+// dense containers switch to the plain bitmap of 2^16 bits.
+
+const (
+	arrayMaxSize = 4096
+	bitmapWords  = (1 << 16) / 64
+)
+
+const (
+	arrayContainer uint8 = iota
+	bitmapContainer
+)
+
+var ErrCorruptedBitmap = errors.New("corrupted bitmap")
+
+type container struct {
+	key    uint16
+	array  []uint16
+	bitmap []uint64
+	count  int
+}
+
+type Bitmap struct {
+	containers []*container
+}
+
+func NewBitmap(values ...uint32) *Bitmap {
+	bitmap := &Bitmap{}
+	for _, value := range values {
+		bitmap.Add(value)
+	}
+	return bitmap
+}
+
+// newRangeBitmap returns bitmap with all values in [0, size)
+func newRangeBitmap(size uint32) *Bitmap {
+	bitmap := &Bitmap{}
+	for start := uint64(0); start < uint64(size); start += 1 << 16 {
+		count := int(min(uint64(size)-start, 1<<16))
+		c := &container{key: uint16(start >> 16), bitmap: make([]uint64, bitmapWords), count: count}
+		for idx := 0; idx < count/64; idx++ {
+			c.bitmap[idx] = ^uint64(0)
+		}
+		if rest := count % 64; rest != 0 {
+			c.bitmap[count/64] = (1 << rest) - 1
+		}
+		bitmap.containers = append(bitmap.containers, c.normalize())
+	}
+	return bitmap
+}
+
+func (b *Bitmap) Add(value uint32) {
+	key, low := uint16(value>>16), uint16(value)
+	idx, found := b.search(key)
+	if !found {
+		b.containers = slices.Insert(b.containers, idx, &container{key: key})
+	}
+	b.containers[idx].add(low)
+}
+
+func (b *Bitmap) Contains(value uint32) bool {
+	idx, found := b.search(uint16(value >> 16))
+	return found && b.containers[idx].contains(uint16(value))
+}
+
+func (b *Bitmap) Cardinality() int {
+	var count int
+	for _, c := range b.containers {
+		count += c.count
+	}
+	return count
+}
+
+func (b *Bitmap) All() iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
+		for _, c := range b.containers {
+			high := uint32(c.key) << 16
+			for low := range c.all() {
+				if !yield(high | uint32(low)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (b *Bitmap) And(other *Bitmap) *Bitmap {
+	result := &Bitmap{}
+	lhs, rhs := 0, 0
+	for lhs < len(b.containers) && rhs < len(other.containers) {
+		switch l, r := b.containers[lhs], other.containers[rhs]; {
+		case l.key < r.key:
+			lhs++
+		case l.key > r.key:
+			rhs++
+		default:
+			result.append(and(l, r))
+			lhs++
+			rhs++
+		}
+	}
+	return result
+}
+
+func (b *Bitmap) Or(other *Bitmap) *Bitmap {
+	result := &Bitmap{}
+	lhs, rhs := 0, 0
+	for lhs < len(b.containers) || rhs < len(other.containers) {
+		switch {
+		case rhs == len(other.containers) || lhs < len(b.containers) && b.containers[lhs].key < other.containers[rhs].key:
+			result.append(b.containers[lhs].clone())
+			lhs++
+		case lhs == len(b.containers) || b.containers[lhs].key > other.containers[rhs].key:
+			result.append(other.containers[rhs].clone())
+			rhs++
+		default:
+			result.append(or(b.containers[lhs], other.containers[rhs]))
+			lhs++
+			rhs++
+		}
+	}
+	return result
+}
+
+func (b *Bitmap) AndNot(other *Bitmap) *Bitmap {
+	result := &Bitmap{}
+	rhs := 0
+	for _, l := range b.containers {
+		for rhs < len(other.containers) && other.containers[rhs].key < l.key {
+			rhs++
+		}
+		if rhs < len(other.containers) && other.containers[rhs].key == l.key {
+			result.append(andNot(l, other.containers[rhs]))
+		} else {
+			result.append(l.clone())
+		}
+	}
+	return result
+}
+
+func (b *Bitmap) Equal(other *Bitmap) bool {
+	return slices.Equal(slices.Collect(b.All()), slices.Collect(other.All()))
+}
+
+func (b *Bitmap) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	if err := binary.Write(counter, binary.LittleEndian, uint32(len(b.containers))); err != nil {
+		return counter.n, err
+	}
+
+	for _, c := range b.containers {
+		kind, data := arrayContainer, any(c.array)
+		if c.bitmap != nil {
+			kind, data = bitmapContainer, c.bitmap
+		}
+
+		header := struct {
+			Key   uint16
+			Kind  uint8
+			Count uint32
+		}{c.key, kind, uint32(c.count)}
+
+		if err := binary.Write(counter, binary.LittleEndian, header); err != nil {
+			return counter.n, err
+		}
+		if err := binary.Write(counter, binary.LittleEndian, data); err != nil {
+			return counter.n, err
+		}
+	}
+
+	return counter.n, nil
+}
+
+func (b *Bitmap) ReadFrom(r io.Reader) (int64, error) {
+	counter := &countingReader{r: r}
+
+	var size uint32
+	if err := binary.Read(counter, binary.LittleEndian, &size); err != nil {
+		return counter.n, err
+	}
+
+	containers := make([]*container, 0, min(size, 1<<16))
+	for range size {
+		var header struct {
+			Key   uint16
+			Kind  uint8
+			Count uint32
+		}
+		if err := binary.Read(counter, binary.LittleEndian, &header); err != nil {
+			return counter.n, err
+		}
+
+		c := &container{key: header.Key, count: int(header.Count)}
+		switch {
+		case header.Kind == arrayContainer && header.Count <= arrayMaxSize:
+			c.array = make([]uint16, header.Count)
+			if err := binary.Read(counter, binary.LittleEndian, c.array); err != nil {
+				return counter.n, err
+			}
+		case header.Kind == bitmapContainer && header.Count <= 1<<16:
+			c.bitmap = make([]uint64, bitmapWords)
+			if err := binary.Read(counter, binary.LittleEndian, c.bitmap); err != nil {
+				return counter.n, err
+			}
+		default:
+			return counter.n, ErrCorruptedBitmap
+		}
+
+		if !c.valid() || len(containers) > 0 && containers[len(containers)-1].key >= c.key {
+			return counter.n, ErrCorruptedBitmap
+		}
+		containers = append(containers, c)
+	}
+
+	b.containers = containers
+	return counter.n, nil
+}
+
+func (b *Bitmap) search(key uint16) (int, bool) {
+	return slices.BinarySearchFunc(b.containers, key, func(c *container, key uint16) int {
+		return int(c.key) - int(key)
+	})
+}
+
+func (b *Bitmap) append(c *container) {
+	if c.count > 0 {
+		b.containers = append(b.containers, c)
+	}
+}
+
+func (c *container) add(value uint16) {
+	if c.bitmap != nil {
+		word, mask := value/64, uint64(1)<<(value%64)
+		if c.bitmap[word]&mask == 0 {
+			c.bitmap[word] |= mask
+			c.count++
+		}
+		return
+	}
+
+	idx, found := slices.BinarySearch(c.array, value)
+	if found {
+		return
+	}
+
+	c.array = slices.Insert(c.array, idx, value)
+	c.count++
+	if c.count > arrayMaxSize {
+		c.toBitmap()
+	}
+}
+
+func (c *container) contains(value uint16) bool {
+	if c.bitmap != nil {
+		return c.bitmap[value/64]&(1<<(value%64)) != 0
+	}
+	_, found := slices.BinarySearch(c.array, value)
+	return found
+}
+
+func (c *container) all() iter.Seq[uint16] {
+	return func(yield func(uint16) bool) {
+		if c.bitmap == nil {
+			for _, value := range c.array {
+				if !yield(value) {
+					return
+				}
+			}
+			return
+		}
+
+		for idx, word := range c.bitmap {
+			for word != 0 {
+				if !yield(uint16(idx*64 + bits.TrailingZeros64(word))) {
+					return
+				}
+				word &= word - 1
+			}
+		}
+	}
+}
+
+func (c *container) clone() *container {
+	return &container{
+		key:    c.key,
+		array:  slices.Clone(c.array),
+		bitmap: slices.Clone(c.bitmap),
+		count:  c.count,
+	}
+}
+
+func (c *container) toBitmap() {
+	c.bitmap = make([]uint64, bitmapWords)
+	for _, value := range c.array {
+		c.bitmap[value/64] |= 1 << (value % 64)
+	}
+	c.array = nil
+}
+
+// valid checks invariants of the container read from untrusted data:
+// array is sorted without duplicates, bitmap is used only for dense
+// containers and the stored count matches the content
+func (c *container) valid() bool {
+	if c.bitmap == nil {
+		for idx := 1; idx < len(c.array); idx++ {
+			if c.array[idx-1] >= c.array[idx] {
+				return false
+			}
+		}
+		return c.count > 0 && c.count <= arrayMaxSize
+	}
+
+	var count int
+	for _, word := range c.bitmap {
+		count += bits.OnesCount64(word)
+	}
+	return c.count > arrayMaxSize && count == c.count
+}
+
+// normalize converts sparse bitmap container back to the array one
+func (c *container) normalize() *container {
+	if c.bitmap == nil || c.count > arrayMaxSize {
+		return c
+	}
+
+	array := make([]uint16, 0, c.count)
+	for value := range c.all() {
+		array = append(array, value)
+	}
+	c.array, c.bitmap = array, nil
+	return c
+}
+
+func and(lhs, rhs *container) *container {
+	result := &container{key: lhs.key}
+	if lhs.bitmap != nil && rhs.bitmap != nil {
+		result.bitmap = make([]uint64, bitmapWords)
+		for idx := range result.bitmap {
+			result.bitmap[idx] = lhs.bitmap[idx] & rhs.bitmap[idx]
+			result.count += bits.OnesCount64(result.bitmap[idx])
+		}
+		return result.normalize()
+	}
+
+	if lhs.bitmap != nil {
+		lhs, rhs = rhs, lhs
+	}
+	for _, value := range lhs.array {
+		if rhs.contains(value) {
+			result.array = append(result.array, value)
+		}
+	}
+	result.count = len(result.array)
+	return result
+}
+
+func or(lhs, rhs *container) *container {
+	if lhs.bitmap == nil && rhs.bitmap == nil && lhs.count+rhs.count <= arrayMaxSize {
+		result := &container{key: lhs.key, array: make([]uint16, 0, lhs.count+rhs.count)}
+		l, r := 0, 0
+		for l < len(lhs.array) || r < len(rhs.array) {
+			switch {
+			case r == len(rhs.array) || l < len(lhs.array) && lhs.array[l] < rhs.array[r]:
+				result.array = append(result.array, lhs.array[l])
+				l++
+			case l == len(lhs.array) || lhs.array[l] > rhs.array[r]:
+				result.array = append(result.array, rhs.array[r])
+				r++
+			default:
+				result.array = append(result.array, lhs.array[l])
+				l++
+				r++
+			}
+		}
+		result.count = len(result.array)
+		return result
+	}
+
+	result := lhs.clone()
+	if result.bitmap == nil {
+		result.toBitmap()
+	}
+	for value := range rhs.all() {
+		result.add(value)
+	}
+	return result.normalize()
+}
+
+func andNot(lhs, rhs *container) *container {
+	result := &container{key: lhs.key}
+	if lhs.bitmap != nil && rhs.bitmap != nil {
+		result.bitmap = make([]uint64, bitmapWords)
+		for idx := range result.bitmap {
+			result.bitmap[idx] = lhs.bitmap[idx] &^ rhs.bitmap[idx]
+			result.count += bits.OnesCount64(result.bitmap[idx])
+		}
+		return result.normalize()
+	}
+
+	if lhs.bitmap != nil {
+		result = lhs.clone()
+		for _, value := range rhs.array {
+			word, mask := value/64, uint64(1)<<(value%64)
+			if result.bitmap[word]&mask != 0 {
+				result.bitmap[word] &^= mask
+				result.count--
+			}
+		}
+		return result.normalize()
+	}
+
+	for _, value := range lhs.array {
+		if !rhs.contains(value) {
+			result.array = append(result.array, value)
+		}
+	}
+	result.count = len(result.array)
+	return result
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"unsafe"
+)
+
+var (
+	ErrIntOverflow    = errors.New("integer overflow")
+	ErrDivisionByZero = errors.New("integer division by zero")
+)
+
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+func Add[T Integer](lhs, rhs T) (T, error) {
+	return withError(AddOk(lhs, rhs))
+}
+
+func Sub[T Integer](lhs, rhs T) (T, error) {
+	return withError(SubOk(lhs, rhs))
+}
+
+func Mul[T Integer](lhs, rhs T) (T, error) {
+	return withError(MulOk(lhs, rhs))
+}
+
+func Div[T Integer](lhs, rhs T) (T, error) {
+	if rhs == 0 {
+		return 0, ErrDivisionByZero
+	}
+	return withError(DivOk(lhs, rhs))
+}
+
+func Neg[T Integer](value T) (T, error) {
+	return withError(NegOk(value))
+}
+
+// AddOk, SubOk, MulOk, DivOk and NegOk return false instead of error,
+// the result is zero in this case.
+
+func AddOk[T Integer](lhs, rhs T) (T, bool) {
+	result := lhs + rhs
+	if (rhs > 0) != (result > lhs) {
+		return 0, false
+	}
+	return result, true
+}
+
+func SubOk[T Integer](lhs, rhs T) (T, bool) {
+	result := lhs - rhs
+	if (rhs > 0) != (result < lhs) {
+		return 0, false
+	}
+	return result, true
+}
+
+func MulOk[T Integer](lhs, rhs T) (T, bool) {
+	if lhs == 0 || rhs == 0 {
+		return 0, true
+	}
+
+	if isSigned[T]() && rhs == ^T(0) && lhs == minOf[T]() {
+		return 0, false
+	}
+
+	result := lhs * rhs
+	if result/rhs != lhs {
+		return 0, false
+	}
+	return result, true
+}
+
+func DivOk[T Integer](lhs, rhs T) (T, bool) {
+	if rhs == 0 {
+		return 0, false
+	}
+	if isSigned[T]() && rhs == ^T(0) && lhs == minOf[T]() {
+		return 0, false
+	}
+	return lhs / rhs, true
+}
+
+func NegOk[T Integer](value T) (T, bool) {
+	if isSigned[T]() && value == minOf[T]() || !isSigned[T]() && value != 0 {
+		return 0, false
+	}
+	return -value, true
+}
+
+// Saturating variants clamp the result to the bounds of the type.
+
+func SaturatingAdd[T Integer](lhs, rhs T) T {
+	if result, ok := AddOk(lhs, rhs); ok {
+		return result
+	}
+	if rhs > 0 {
+		return maxOf[T]()
+	}
+	return minOf[T]()
+}
+
+func SaturatingSub[T Integer](lhs, rhs T) T {
+	if result, ok := SubOk(lhs, rhs); ok {
+		return result
+	}
+	if rhs > 0 {
+		return minOf[T]()
+	}
+	return maxOf[T]()
+}
+
+func SaturatingMul[T Integer](lhs, rhs T) T {
+	if result, ok := MulOk(lhs, rhs); ok {
+		return result
+	}
+	if (lhs < 0) != (rhs < 0) {
+		return minOf[T]()
+	}
+	return maxOf[T]()
+}
+
+// SaturatingDiv panics on zero divisor like the built-in division
+func SaturatingDiv[T Integer](lhs, rhs T) T {
+	if result, ok := DivOk(lhs, rhs); ok {
+		return result
+	}
+	if rhs == 0 {
+		return lhs / rhs
+	}
+	return maxOf[T]()
+}
+
+func SaturatingNeg[T Integer](value T) T {
+	if result, ok := NegOk(value); ok {
+		return result
+	}
+	if isSigned[T]() {
+		return maxOf[T]()
+	}
+	return 0
+}
+
+// Checked keeps the first error of the chain of operations,
+// operations after the error are ignored.
+type Checked[T Integer] struct {
+	value T
+	err   error
+}
+
+func Of[T Integer](value T) Checked[T] {
+	return Checked[T]{value: value}
+}
+
+func (c Checked[T]) Add(value T) Checked[T] {
+	return c.apply(value, Add[T])
+}
+
+func (c Checked[T]) Sub(value T) Checked[T] {
+	return c.apply(value, Sub[T])
+}
+
+func (c Checked[T]) Mul(value T) Checked[T] {
+	return c.apply(value, Mul[T])
+}
+
+func (c Checked[T]) Div(value T) Checked[T] {
+	return c.apply(value, Div[T])
+}
+
+func (c Checked[T]) Neg() Checked[T] {
+	return c.apply(0, func(value, _ T) (T, error) {
+		return Neg(value)
+	})
+}
+
+func (c Checked[T]) Value() (T, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.value, nil
+}
+
+func (c Checked[T]) Err() error {
+	return c.err
+}
+
+func (c Checked[T]) Overflowed() bool {
+	return errors.Is(c.err, ErrIntOverflow)
+}
+
+func (c Checked[T]) apply(value T, operation func(T, T) (T, error)) Checked[T] {
+	if c.err != nil {
+		return c
+	}
+
+	result, err := operation(c.value, value)
+	if err != nil {
+		return Checked[T]{value: c.value, err: err}
+	}
+	return Checked[T]{value: result}
+}
+
+func withError[T Integer](result T, ok bool) (T, error) {
+	if !ok {
+		return 0, ErrIntOverflow
+	}
+	return result, nil
+}
+
+func isSigned[T Integer]() bool {
+	return ^T(0) < 0
+}
+
+func maxOf[T Integer]() T {
+	if isSigned[T]() {
+		var value T
+		return T(1)<<(unsafe.Sizeof(value)*8-1) - 1
+	}
+	return ^T(0)
+}
+
+func minOf[T Integer]() T {
+	if isSigned[T]() {
+		return ^maxOf[T]()
+	}
+	return 0
+}
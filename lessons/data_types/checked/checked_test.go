@@ -0,0 +1,149 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v .
+
+func TestExhaustiveInt8(t *testing.T) {
+	for lhs := math.MinInt8; lhs <= math.MaxInt8; lhs++ {
+		for rhs := math.MinInt8; rhs <= math.MaxInt8; rhs++ {
+			checkOperation(t, "add", int8(lhs), int8(rhs), lhs+rhs, Add[int8], SaturatingAdd[int8])
+			checkOperation(t, "sub", int8(lhs), int8(rhs), lhs-rhs, Sub[int8], SaturatingSub[int8])
+			checkOperation(t, "mul", int8(lhs), int8(rhs), lhs*rhs, Mul[int8], SaturatingMul[int8])
+			if rhs != 0 {
+				checkOperation(t, "div", int8(lhs), int8(rhs), lhs/rhs, Div[int8], SaturatingDiv[int8])
+			}
+		}
+
+		neg := func(value, _ int8) (int8, error) { return Neg(value) }
+		saturatingNeg := func(value, _ int8) int8 { return SaturatingNeg(value) }
+		checkOperation(t, "neg", int8(lhs), 0, -lhs, neg, saturatingNeg)
+	}
+}
+
+func TestExhaustiveUint8(t *testing.T) {
+	for lhs := 0; lhs <= math.MaxUint8; lhs++ {
+		for rhs := 0; rhs <= math.MaxUint8; rhs++ {
+			checkOperation(t, "add", uint8(lhs), uint8(rhs), lhs+rhs, Add[uint8], SaturatingAdd[uint8])
+			checkOperation(t, "sub", uint8(lhs), uint8(rhs), lhs-rhs, Sub[uint8], SaturatingSub[uint8])
+			checkOperation(t, "mul", uint8(lhs), uint8(rhs), lhs*rhs, Mul[uint8], SaturatingMul[uint8])
+			if rhs != 0 {
+				checkOperation(t, "div", uint8(lhs), uint8(rhs), lhs/rhs, Div[uint8], SaturatingDiv[uint8])
+			}
+		}
+
+		neg := func(value, _ uint8) (uint8, error) { return Neg(value) }
+		saturatingNeg := func(value, _ uint8) uint8 { return SaturatingNeg(value) }
+		checkOperation(t, "neg", uint8(lhs), 0, -lhs, neg, saturatingNeg)
+	}
+}
+
+func checkOperation[T int8 | uint8](
+	t *testing.T,
+	name string,
+	lhs, rhs T,
+	exact int,
+	checked func(T, T) (T, error),
+	saturating func(T, T) T,
+) {
+	t.Helper()
+
+	low, high := int(minOf[T]()), int(maxOf[T]())
+	result, err := checked(lhs, rhs)
+	if exact < low || exact > high {
+		if !assert.ErrorIs(t, err, ErrIntOverflow, "%s(%d, %d)", name, lhs, rhs) {
+			t.FailNow()
+		}
+		assert.Equal(t, T(min(max(exact, low), high)), saturating(lhs, rhs), "%s(%d, %d)", name, lhs, rhs)
+		return
+	}
+
+	if !assert.NoError(t, err, "%s(%d, %d)", name, lhs, rhs) {
+		t.FailNow()
+	}
+	assert.Equal(t, T(exact), result)
+	assert.Equal(t, T(exact), saturating(lhs, rhs))
+}
+
+func TestInt64Bounds(t *testing.T) {
+	tests := map[string]struct {
+		ok       bool
+		expected bool
+	}{
+		"max + 1":     {ok: second(AddOk[int64](math.MaxInt64, 1))},
+		"min - 1":     {ok: second(SubOk[int64](math.MinInt64, 1))},
+		"min * -1":    {ok: second(MulOk[int64](math.MinInt64, -1))},
+		"-1 * min":    {ok: second(MulOk[int64](-1, math.MinInt64))},
+		"min / -1":    {ok: second(DivOk[int64](math.MinInt64, -1))},
+		"-min":        {ok: second(NegOk[int64](math.MinInt64))},
+		"max * 2":     {ok: second(MulOk[uint64](math.MaxUint64, 2))},
+		"0 - 1":       {ok: second(SubOk[uint](0, 1))},
+		"max - -1":    {ok: second(SubOk[int](math.MaxInt, -1))},
+		"min + max":   {ok: second(AddOk[int](math.MinInt, math.MaxInt)), expected: true},
+		"max * -1":    {ok: second(MulOk[int](math.MaxInt, -1)), expected: true},
+		"2^31 * 2^31": {ok: second(MulOk[int64](1<<31, 1<<31)), expected: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.ok)
+		})
+	}
+}
+
+func second[T any](_ T, ok bool) bool {
+	return ok
+}
+
+func TestDivisionByZero(t *testing.T) {
+	_, err := Div(10, 0)
+	assert.ErrorIs(t, err, ErrDivisionByZero)
+
+	_, ok := DivOk(10, 0)
+	assert.False(t, ok)
+
+	assert.Panics(t, func() { SaturatingDiv(10, 0) })
+}
+
+type Cents int64
+
+func TestCheckedChain(t *testing.T) {
+	type item struct {
+		price    Cents
+		quantity Cents
+	}
+
+	total := func(items []item) (Cents, error) {
+		sum := Of[Cents](0)
+		for _, item := range items {
+			cost, err := Mul(item.price, item.quantity)
+			if err != nil {
+				return 0, err
+			}
+			sum = sum.Add(cost)
+		}
+		return sum.Value()
+	}
+
+	result, err := total([]item{{price: 1999, quantity: 3}, {price: 500, quantity: 10}})
+	assert.NoError(t, err)
+	assert.Equal(t, Cents(10997), result)
+
+	value := Of[Cents](math.MaxInt64).Add(1).Sub(10).Neg()
+	assert.True(t, value.Overflowed())
+	_, err = value.Value()
+	assert.ErrorIs(t, err, ErrIntOverflow)
+
+	value = Of[Cents](100).Div(0).Add(1)
+	assert.False(t, value.Overflowed())
+	assert.ErrorIs(t, value.Err(), ErrDivisionByZero)
+
+	result, err = Of[Cents](7).Mul(6).Sub(2).Div(4).Neg().Value()
+	assert.NoError(t, err)
+	assert.Equal(t, Cents(-10), result)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAddress = errors.New("invalid IPv4 address")
+	ErrInvalidPrefix  = errors.New("invalid IPv4 prefix")
+)
+
+type IPv4 uint32
+
+func IPv4FromOctets(octets [4]byte) IPv4 {
+	return IPv4(octets[0])<<24 | IPv4(octets[1])<<16 | IPv4(octets[2])<<8 | IPv4(octets[3])
+}
+
+// ParseIPv4 accepts only dotted decimal form: exactly four octets
+// without signs, spaces and leading zeros.
+func ParseIPv4(address string) (IPv4, error) {
+	const octetsCount = 4
+
+	var octets [octetsCount]byte
+	rest := address
+	for idx := range octetsCount {
+		segment := rest
+		if idx < octetsCount-1 {
+			var found bool
+			segment, rest, found = strings.Cut(rest, ".")
+			if !found {
+				return 0, fmt.Errorf("%w: %q", ErrInvalidAddress, address)
+			}
+		}
+
+		number, ok := parseDecimal(segment, 255)
+		if !ok {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidAddress, address)
+		}
+		octets[idx] = byte(number)
+	}
+
+	return IPv4FromOctets(octets), nil
+}
+
+func MustParseIPv4(address string) IPv4 {
+	ip, err := ParseIPv4(address)
+	if err != nil {
+		panic(err)
+	}
+	return ip
+}
+
+func (ip IPv4) Octets() [4]byte {
+	return [4]byte{byte(ip >> 24), byte(ip >> 16), byte(ip >> 8), byte(ip)}
+}
+
+func (ip IPv4) String() string {
+	buffer := make([]byte, 0, len("255.255.255.255"))
+	for idx, octet := range ip.Octets() {
+		if idx > 0 {
+			buffer = append(buffer, '.')
+		}
+		buffer = strconv.AppendUint(buffer, uint64(octet), 10)
+	}
+	return string(buffer)
+}
+
+type Prefix struct {
+	addr IPv4
+	bits uint8
+}
+
+func NewPrefix(addr IPv4, bits int) (Prefix, error) {
+	if bits < 0 || bits > 32 {
+		return Prefix{}, fmt.Errorf("%w: prefix length %d", ErrInvalidPrefix, bits)
+	}
+	return Prefix{addr: addr, bits: uint8(bits)}, nil
+}
+
+// ParsePrefix parses CIDR notation, the address may have host bits set
+// like in "192.168.1.10/24", use Masked to get the network prefix.
+func ParsePrefix(prefix string) (Prefix, error) {
+	address, length, found := strings.Cut(prefix, "/")
+	if !found {
+		return Prefix{}, fmt.Errorf("%w: %q", ErrInvalidPrefix, prefix)
+	}
+
+	addr, err := ParseIPv4(address)
+	if err != nil {
+		return Prefix{}, fmt.Errorf("%w: %q", ErrInvalidPrefix, prefix)
+	}
+
+	bits, ok := parseDecimal(length, 32)
+	if !ok {
+		return Prefix{}, fmt.Errorf("%w: %q", ErrInvalidPrefix, prefix)
+	}
+
+	return Prefix{addr: addr, bits: uint8(bits)}, nil
+}
+
+func MustParsePrefix(prefix string) Prefix {
+	p, err := ParsePrefix(prefix)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p Prefix) Addr() IPv4 {
+	return p.addr
+}
+
+func (p Prefix) Bits() int {
+	return int(p.bits)
+}
+
+func (p Prefix) Netmask() IPv4 {
+	return ^IPv4(0) << (32 - p.bits)
+}
+
+func (p Prefix) Network() IPv4 {
+	return p.addr & p.Netmask()
+}
+
+func (p Prefix) Broadcast() IPv4 {
+	return p.addr | ^p.Netmask()
+}
+
+func (p Prefix) Masked() Prefix {
+	return Prefix{addr: p.Network(), bits: p.bits}
+}
+
+func (p Prefix) Size() uint64 {
+	return 1 << (32 - p.bits)
+}
+
+func (p Prefix) Contains(ip IPv4) bool {
+	return ip&p.Netmask() == p.Network()
+}
+
+func (p Prefix) Overlaps(other Prefix) bool {
+	return p.Contains(other.Network()) || other.Contains(p.Network())
+}
+
+// All yields every address of the prefix from network to broadcast
+func (p Prefix) All() iter.Seq[IPv4] {
+	return Range(p.Network(), p.Broadcast())
+}
+
+func (p Prefix) String() string {
+	return p.addr.String() + "/" + strconv.Itoa(int(p.bits))
+}
+
+// Range yields addresses from first to last inclusive
+func Range(first, last IPv4) iter.Seq[IPv4] {
+	return func(yield func(IPv4) bool) {
+		if first > last {
+			return
+		}
+
+		for ip := first; ; ip++ {
+			if !yield(ip) || ip == last {
+				return
+			}
+		}
+	}
+}
+
+func parseDecimal(segment string, limit int) (int, bool) {
+	if len(segment) == 0 || len(segment) > 3 {
+		return 0, false
+	}
+	if len(segment) > 1 && segment[0] == '0' {
+		return 0, false
+	}
+
+	var number int
+	for idx := range len(segment) {
+		digit := segment[idx]
+		if digit < '0' || digit > '9' {
+			return 0, false
+		}
+		number = number*10 + int(digit-'0')
+	}
+
+	return number, number <= limit
+}
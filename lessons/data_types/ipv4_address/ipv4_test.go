@@ -0,0 +1,241 @@
+package main
+
+import (
+	"math/rand"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v .
+// go test -fuzz=FuzzParseIPv4 .
+
+func TestParseIPv4(t *testing.T) {
+	tests := map[string]struct {
+		address string
+		result  IPv4
+		valid   bool
+	}{
+		"zero address":      {address: "0.0.0.0", result: 0, valid: true},
+		"broadcast address": {address: "255.255.255.255", result: 0xFFFFFFFF, valid: true},
+		"private address":   {address: "192.168.1.10", result: 0xC0A8010A, valid: true},
+		"plus sign":         {address: "+1.2.3.4"},
+		"minus sign":        {address: "1.-2.3.4"},
+		"leading zero":      {address: "01.2.3.4"},
+		"octet overflow":    {address: "1.2.3.256"},
+		"too few octets":    {address: "1.2.3"},
+		"too many octets":   {address: "1.2.3.4.5"},
+		"empty octet":       {address: "1..3.4"},
+		"trailing dot":      {address: "1.2.3.4."},
+		"spaces":            {address: " 1.2.3.4"},
+		"empty string":      {address: ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := ParseIPv4(test.address)
+			if test.valid {
+				require.NoError(t, err)
+				assert.Equal(t, test.result, result)
+				assert.Equal(t, test.address, result.String())
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidAddress)
+			}
+
+			converted, err := Convert(test.address)
+			assert.Equal(t, test.valid, err == nil)
+			assert.Equal(t, uint32(test.result), converted)
+		})
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	tests := map[string]struct {
+		prefix    string
+		netmask   string
+		network   string
+		broadcast string
+		size      uint64
+	}{
+		"host bits set": {
+			prefix:    "192.168.1.10/24",
+			netmask:   "255.255.255.0",
+			network:   "192.168.1.0",
+			broadcast: "192.168.1.255",
+			size:      256,
+		},
+		"odd length": {
+			prefix:    "10.20.30.40/13",
+			netmask:   "255.248.0.0",
+			network:   "10.16.0.0",
+			broadcast: "10.23.255.255",
+			size:      1 << 19,
+		},
+		"single host": {
+			prefix:    "8.8.8.8/32",
+			netmask:   "255.255.255.255",
+			network:   "8.8.8.8",
+			broadcast: "8.8.8.8",
+			size:      1,
+		},
+		"whole space": {
+			prefix:    "1.2.3.4/0",
+			netmask:   "0.0.0.0",
+			network:   "0.0.0.0",
+			broadcast: "255.255.255.255",
+			size:      1 << 32,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			prefix, err := ParsePrefix(test.prefix)
+			require.NoError(t, err)
+			assert.Equal(t, test.prefix, prefix.String())
+			assert.Equal(t, test.netmask, prefix.Netmask().String())
+			assert.Equal(t, test.network, prefix.Network().String())
+			assert.Equal(t, test.broadcast, prefix.Broadcast().String())
+			assert.Equal(t, test.size, prefix.Size())
+			assert.True(t, prefix.Contains(prefix.Network()))
+			assert.True(t, prefix.Contains(prefix.Broadcast()))
+		})
+	}
+
+	for _, prefix := range []string{"1.2.3.4", "1.2.3.4/33", "1.2.3.4/08", "1.2.3.4/", "1.2.3/8", "1.2.3.4/+8"} {
+		_, err := ParsePrefix(prefix)
+		assert.ErrorIs(t, err, ErrInvalidPrefix, prefix)
+	}
+}
+
+func TestPrefixIteration(t *testing.T) {
+	prefix := MustParsePrefix("10.0.0.5/30")
+
+	var addresses []string
+	for ip := range prefix.All() {
+		addresses = append(addresses, ip.String())
+	}
+	assert.Equal(t, []string{"10.0.0.4", "10.0.0.5", "10.0.0.6", "10.0.0.7"}, addresses)
+
+	last := slices.Collect(Range(MustParseIPv4("255.255.255.254"), MustParseIPv4("255.255.255.255")))
+	assert.Equal(t, []IPv4{0xFFFFFFFE, 0xFFFFFFFF}, last)
+	assert.Empty(t, slices.Collect(Range(2, 1)))
+}
+
+func TestSetLookup(t *testing.T) {
+	var set Set
+	for _, prefix := range []string{"0.0.0.0/0", "10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "192.168.0.0/16"} {
+		assert.True(t, set.Insert(MustParsePrefix(prefix)))
+	}
+	assert.False(t, set.Insert(MustParsePrefix("10.1.2.3/24")))
+	assert.Equal(t, 5, set.Len())
+
+	tests := map[string]string{
+		"10.1.2.3":    "10.1.2.0/24",
+		"10.1.3.3":    "10.1.0.0/16",
+		"10.2.0.1":    "10.0.0.0/8",
+		"192.168.5.5": "192.168.0.0/16",
+		"8.8.8.8":     "0.0.0.0/0",
+	}
+
+	for address, expected := range tests {
+		prefix, found := set.Lookup(MustParseIPv4(address))
+		assert.True(t, found, address)
+		assert.Equal(t, expected, prefix.String(), address)
+	}
+
+	assert.True(t, set.Remove(MustParsePrefix("10.1.0.0/16")))
+	assert.False(t, set.Remove(MustParsePrefix("10.1.0.0/16")))
+	assert.False(t, set.Contains(MustParsePrefix("10.1.0.0/16")))
+	assert.True(t, set.Contains(MustParsePrefix("10.1.2.0/24")))
+
+	prefix, _ := set.Lookup(MustParseIPv4("10.1.3.3"))
+	assert.Equal(t, "10.0.0.0/8", prefix.String())
+
+	var prefixes []string
+	for prefix := range set.All() {
+		prefixes = append(prefixes, prefix.String())
+	}
+	assert.Equal(t, []string{"0.0.0.0/0", "10.0.0.0/8", "10.1.2.0/24", "192.168.0.0/16"}, prefixes)
+
+	assert.True(t, set.Remove(MustParsePrefix("0.0.0.0/0")))
+	_, found := set.Lookup(MustParseIPv4("8.8.8.8"))
+	assert.False(t, found)
+}
+
+func TestSetMatchesLinearScan(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+
+	var set Set
+	var prefixes []Prefix
+	for range 500 {
+		prefix, _ := NewPrefix(IPv4(random.Uint32()), 4+random.Intn(25))
+		if set.Insert(prefix) {
+			prefixes = append(prefixes, prefix.Masked())
+		}
+	}
+
+	for range 10_000 {
+		ip := IPv4(random.Uint32())
+		if random.Intn(2) == 0 {
+			ip = prefixes[random.Intn(len(prefixes))].Network() | IPv4(random.Intn(16))
+		}
+
+		var expected Prefix
+		var expectedFound bool
+		for _, prefix := range prefixes {
+			if prefix.Contains(ip) && (!expectedFound || prefix.Bits() > expected.Bits()) {
+				expected, expectedFound = prefix, true
+			}
+		}
+
+		result, found := set.Lookup(ip)
+		assert.Equal(t, expectedFound, found)
+		assert.Equal(t, expected, result)
+	}
+}
+
+func FuzzParseIPv4(f *testing.F) {
+	for _, address := range []string{"1.2.3.4", "255.255.255.255", "01.2.3.4", "1.2.3.4.5", "::ffff:1.2.3.4", "+1.2.3.4"} {
+		f.Add(address)
+	}
+
+	f.Fuzz(func(t *testing.T, address string) {
+		ip, err := ParseIPv4(address)
+		expected, expectedErr := netip.ParseAddr(address)
+		if expectedErr != nil || !expected.Is4() {
+			assert.Error(t, err)
+			return
+		}
+
+		require.NoError(t, err)
+		assert.Equal(t, expected.As4(), ip.Octets())
+		assert.Equal(t, expected.String(), ip.String())
+	})
+}
+
+func FuzzParsePrefix(f *testing.F) {
+	for _, prefix := range []string{"10.0.0.0/8", "1.2.3.4/32", "1.2.3.4/0", "1.2.3.4/08", "1.2.3.4/33", "::/0"} {
+		f.Add(prefix)
+	}
+
+	f.Fuzz(func(t *testing.T, prefix string) {
+		result, err := ParsePrefix(prefix)
+		expected, expectedErr := netip.ParsePrefix(prefix)
+		if expectedErr != nil || !expected.Addr().Is4() {
+			assert.Error(t, err)
+			return
+		}
+
+		require.NoError(t, err)
+		assert.Equal(t, expected.String(), result.String())
+		assert.Equal(t, expected.Masked().Addr().As4(), result.Network().Octets())
+
+		masked := expected.Masked()
+		for _, ip := range []IPv4{result.Network(), result.Broadcast(), result.Broadcast() + 1, result.Network() - 1} {
+			assert.Equal(t, masked.Contains(netip.AddrFrom4(ip.Octets())), result.Contains(ip))
+		}
+	})
+}
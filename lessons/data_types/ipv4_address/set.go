@@ -0,0 +1,129 @@
+package main
+
+import "iter"
+
+// Set is a binary radix tree over prefixes: every level of the tree
+// corresponds to one bit of the address starting from the highest one,
+// so the deepest stored prefix on the path of an address is the longest match.
+type Set struct {
+	root *node
+	size int
+}
+
+type node struct {
+	children [2]*node
+	prefix   Prefix
+	present  bool
+}
+
+func (s *Set) Insert(prefix Prefix) bool {
+	prefix = prefix.Masked()
+	if s.root == nil {
+		s.root = &node{}
+	}
+
+	current := s.root
+	for depth := range prefix.bits {
+		bit := bitAt(prefix.addr, depth)
+		if current.children[bit] == nil {
+			current.children[bit] = &node{}
+		}
+		current = current.children[bit]
+	}
+
+	if current.present {
+		return false
+	}
+
+	current.prefix, current.present = prefix, true
+	s.size++
+	return true
+}
+
+func (s *Set) Remove(prefix Prefix) bool {
+	prefix = prefix.Masked()
+
+	path := make([]*node, 0, prefix.bits+1)
+	current := s.root
+	for depth := range prefix.bits {
+		if current == nil {
+			return false
+		}
+		path = append(path, current)
+		current = current.children[bitAt(prefix.addr, depth)]
+	}
+
+	if current == nil || !current.present {
+		return false
+	}
+
+	current.present = false
+	s.size--
+
+	// prune branches without prefixes
+	for depth := len(path) - 1; depth >= 0; depth-- {
+		if current.present || current.children[0] != nil || current.children[1] != nil {
+			break
+		}
+		path[depth].children[bitAt(prefix.addr, uint8(depth))] = nil
+		current = path[depth]
+	}
+
+	return true
+}
+
+func (s *Set) Contains(prefix Prefix) bool {
+	prefix = prefix.Masked()
+	current := s.root
+	for depth := range prefix.bits {
+		if current == nil {
+			return false
+		}
+		current = current.children[bitAt(prefix.addr, depth)]
+	}
+	return current != nil && current.present
+}
+
+// Lookup returns the longest prefix containing the address
+func (s *Set) Lookup(ip IPv4) (Prefix, bool) {
+	var result Prefix
+	var found bool
+
+	current := s.root
+	for depth := uint8(0); current != nil; depth++ {
+		if current.present {
+			result, found = current.prefix, true
+		}
+		if depth == 32 {
+			break
+		}
+		current = current.children[bitAt(ip, depth)]
+	}
+
+	return result, found
+}
+
+func (s *Set) Len() int {
+	return s.size
+}
+
+// All yields prefixes in order of network addresses, shorter prefixes first
+func (s *Set) All() iter.Seq[Prefix] {
+	return func(yield func(Prefix) bool) {
+		var walk func(*node) bool
+		walk = func(current *node) bool {
+			if current == nil {
+				return true
+			}
+			if current.present && !yield(current.prefix) {
+				return false
+			}
+			return walk(current.children[0]) && walk(current.children[1])
+		}
+		walk(s.root)
+	}
+}
+
+func bitAt(ip IPv4, depth uint8) int {
+	return int(ip>>(31-depth)) & 1
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+var ErrNoValue = errors.New("option has no value")
+
+type Option[T any] struct {
+	value   T
+	present bool
+}
+
+func Some[T any](value T) Option[T] {
+	return Option[T]{value: value, present: true}
+}
+
+func None[T any]() Option[T] {
+	return Option[T]{}
+}
+
+func FromPointer[T any](pointer *T) Option[T] {
+	if pointer == nil {
+		return None[T]()
+	}
+	return Some(*pointer)
+}
+
+func (o Option[T]) IsSome() bool {
+	return o.present
+}
+
+func (o Option[T]) IsNone() bool {
+	return !o.present
+}
+
+func (o Option[T]) Get() (T, bool) {
+	return o.value, o.present
+}
+
+// Unwrap panics when there is no value
+func (o Option[T]) Unwrap() T {
+	if !o.present {
+		panic(ErrNoValue)
+	}
+	return o.value
+}
+
+func (o Option[T]) OrElse(value T) T {
+	if o.present {
+		return o.value
+	}
+	return value
+}
+
+func (o Option[T]) OrElseGet(fn func() T) T {
+	if o.present {
+		return o.value
+	}
+	return fn()
+}
+
+func (o Option[T]) Pointer() *T {
+	if !o.present {
+		return nil
+	}
+	value := o.value
+	return &value
+}
+
+// OkOr converts option to result with err when there is no value
+func (o Option[T]) OkOr(err error) Result[T] {
+	if o.present {
+		return Ok(o.value)
+	}
+	return Err[T](err)
+}
+
+func MapOption[T, U any](o Option[T], fn func(T) U) Option[U] {
+	if !o.present {
+		return None[U]()
+	}
+	return Some(fn(o.value))
+}
+
+func FlatMapOption[T, U any](o Option[T], fn func(T) Option[U]) Option[U] {
+	if !o.present {
+		return None[U]()
+	}
+	return fn(o.value)
+}
+
+func (o Option[T]) MarshalJSON() ([]byte, error) {
+	if !o.present {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+func (o *Option[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*o = None[T]()
+		return nil
+	}
+
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	*o = Some(value)
+	return nil
+}
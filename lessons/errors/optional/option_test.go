@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v .
+
+func TestOption(t *testing.T) {
+	some := Some(10)
+	none := None[int]()
+
+	assert.True(t, some.IsSome())
+	assert.True(t, none.IsNone())
+	assert.Equal(t, 10, some.Unwrap())
+	assert.PanicsWithValue(t, ErrNoValue, func() { none.Unwrap() })
+
+	assert.Equal(t, 10, some.OrElse(5))
+	assert.Equal(t, 5, none.OrElse(5))
+	assert.Equal(t, 7, none.OrElseGet(func() int { return 7 }))
+
+	value, ok := some.Get()
+	assert.True(t, ok)
+	assert.Equal(t, 10, value)
+
+	assert.Equal(t, 10, *some.Pointer())
+	assert.Nil(t, none.Pointer())
+	assert.Equal(t, some, FromPointer(some.Pointer()))
+	assert.Equal(t, none, FromPointer[int](nil))
+}
+
+func TestOptionCombinators(t *testing.T) {
+	half := func(number int) Option[int] {
+		if number%2 != 0 {
+			return None[int]()
+		}
+		return Some(number / 2)
+	}
+
+	assert.Equal(t, Some("10"), MapOption(Some(10), strconv.Itoa))
+	assert.Equal(t, None[string](), MapOption(None[int](), strconv.Itoa))
+
+	assert.Equal(t, Some(5), FlatMapOption(Some(10), half))
+	assert.Equal(t, None[int](), FlatMapOption(Some(5), half))
+	assert.Equal(t, None[int](), FlatMapOption(None[int](), half))
+
+	errMissing := errors.New("missing")
+	assert.Equal(t, Ok(10), Some(10).OkOr(errMissing))
+	assert.ErrorIs(t, None[int]().OkOr(errMissing).Err(), errMissing)
+}
+
+func TestResult(t *testing.T) {
+	errParse := errors.New("parse error")
+
+	ok := FromPair(strconv.Atoi("42"))
+	failed := Err[int](errParse)
+
+	assert.True(t, ok.IsOk())
+	assert.False(t, failed.IsOk())
+	assert.Equal(t, 42, ok.Unwrap())
+	assert.PanicsWithValue(t, errParse, func() { failed.Unwrap() })
+	assert.Equal(t, 42, ok.OrElse(0))
+	assert.Equal(t, 0, failed.OrElse(0))
+	assert.Equal(t, -1, failed.OrElseGet(func(error) int { return -1 }))
+
+	value, err := ok.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, 42, value)
+
+	assert.Equal(t, Some(42), ok.Option())
+	assert.Equal(t, None[int](), failed.Option())
+
+	assert.ErrorIs(t, Err[int](nil).Err(), ErrInvalidResult)
+	assert.Error(t, FromPair(strconv.Atoi("x")).Err())
+
+	assert.Equal(t, 10, Must(strconv.Atoi("10")))
+	assert.Panics(t, func() { Must(strconv.Atoi("x")) })
+}
+
+func TestResultCombinators(t *testing.T) {
+	double := func(number int) int { return number * 2 }
+
+	assert.Equal(t, Ok(84), MapResult(Ok(42), double))
+	assert.Equal(t, Ok(42), FlatMapResult(Ok("42"), strconv.Atoi))
+
+	parsed := FlatMapResult(Ok("x"), strconv.Atoi)
+	assert.Error(t, parsed.Err())
+	assert.ErrorIs(t, MapResult(parsed, double).Err(), parsed.Err())
+}
+
+type User struct {
+	Name  string         `json:"name"`
+	Email Option[string] `json:"email"`
+	Age   Option[int]    `json:"age"`
+}
+
+func TestOptionJSON(t *testing.T) {
+	tests := map[string]struct {
+		user User
+		data string
+	}{
+		"with values": {
+			user: User{Name: "John", Email: Some("john@example.com"), Age: Some(30)},
+			data: `{"name":"John","email":"john@example.com","age":30}`,
+		},
+		"without values": {
+			user: User{Name: "John"},
+			data: `{"name":"John","email":null,"age":null}`,
+		},
+		"zero value": {
+			user: User{Name: "John", Email: Some(""), Age: Some(0)},
+			data: `{"name":"John","email":"","age":0}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, err := json.Marshal(test.user)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.data, string(data))
+
+			var user User
+			require.NoError(t, json.Unmarshal([]byte(test.data), &user))
+			assert.Equal(t, test.user, user)
+		})
+	}
+
+	var user User
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"John"}`), &user))
+	assert.True(t, user.Email.IsNone())
+	assert.Error(t, json.Unmarshal([]byte(`{"age":"thirty"}`), &user))
+}
+
+func TestResultJSON(t *testing.T) {
+	data, err := json.Marshal(Ok(42))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"value":42}`, string(data))
+
+	var result Result[int]
+	require.NoError(t, json.Unmarshal(data, &result))
+	assert.Equal(t, Ok(42), result)
+
+	data, err = json.Marshal(Err[int](errors.New("not found")))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"error":"not found"}`, string(data))
+
+	require.NoError(t, json.Unmarshal(data, &result))
+	assert.EqualError(t, result.Err(), "not found")
+
+	data, err = json.Marshal(Ok[*int](nil))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"value":null}`, string(data))
+
+	var pointer Result[*int]
+	require.NoError(t, json.Unmarshal(data, &pointer))
+	assert.Equal(t, Ok[*int](nil), pointer)
+
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{}`), &result), ErrInvalidResult)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"value":1,"error":"x"}`), &result), ErrInvalidResult)
+}
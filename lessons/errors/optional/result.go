@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidResult = errors.New("result must contain either value or error")
+
+type Result[T any] struct {
+	value T
+	err   error
+}
+
+func Ok[T any](value T) Result[T] {
+	return Result[T]{value: value}
+}
+
+// Err creates failed result, nil error is replaced with ErrInvalidResult
+func Err[T any](err error) Result[T] {
+	if err == nil {
+		err = ErrInvalidResult
+	}
+	return Result[T]{err: err}
+}
+
+func FromPair[T any](value T, err error) Result[T] {
+	if err != nil {
+		return Err[T](err)
+	}
+	return Ok(value)
+}
+
+// Must panics on error, it is useful for initialization of globals
+func Must[T any](value T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func (r Result[T]) IsOk() bool {
+	return r.err == nil
+}
+
+func (r Result[T]) Err() error {
+	return r.err
+}
+
+func (r Result[T]) Get() (T, error) {
+	return r.value, r.err
+}
+
+// Unwrap panics with the error of the result
+func (r Result[T]) Unwrap() T {
+	return Must(r.Get())
+}
+
+func (r Result[T]) OrElse(value T) T {
+	if r.err != nil {
+		return value
+	}
+	return r.value
+}
+
+func (r Result[T]) OrElseGet(fn func(error) T) T {
+	if r.err != nil {
+		return fn(r.err)
+	}
+	return r.value
+}
+
+func (r Result[T]) Option() Option[T] {
+	if r.err != nil {
+		return None[T]()
+	}
+	return Some(r.value)
+}
+
+func MapResult[T, U any](r Result[T], fn func(T) U) Result[U] {
+	if r.err != nil {
+		return Err[U](r.err)
+	}
+	return Ok(fn(r.value))
+}
+
+// FlatMapResult accepts functions returning (value, error) pairs as well
+func FlatMapResult[T, U any](r Result[T], fn func(T) (U, error)) Result[U] {
+	if r.err != nil {
+		return Err[U](r.err)
+	}
+	return FromPair(fn(r.value))
+}
+
+type resultJSON struct {
+	Value json.RawMessage `json:"value,omitempty"`
+	Error *string         `json:"error,omitempty"`
+}
+
+func (r Result[T]) MarshalJSON() ([]byte, error) {
+	if r.err != nil {
+		message := r.err.Error()
+		return json.Marshal(resultJSON{Error: &message})
+	}
+
+	value, err := json.Marshal(r.value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resultJSON{Value: value})
+}
+
+// UnmarshalJSON restores error only as a message, its type is lost
+func (r *Result[T]) UnmarshalJSON(data []byte) error {
+	var decoded resultJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	switch {
+	case decoded.Error != nil && decoded.Value == nil:
+		*r = Err[T](errors.New(*decoded.Error))
+	case decoded.Error == nil && decoded.Value != nil:
+		var value T
+		if err := json.Unmarshal(decoded.Value, &value); err != nil {
+			return err
+		}
+		*r = Ok(value)
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidResult, data)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// StageFunc is a stage with erased types, it allows to keep stages
+// of different types in one pipeline and to wrap them with middleware
+type StageFunc func(ctx context.Context, value any) (any, error)
+
+type Middleware func(name string, next StageFunc) StageFunc
+
+type stage struct {
+	name string
+	run  StageFunc
+}
+
+func (s stage) call(ctx context.Context, value any) (any, error) {
+	result, err := s.run(ctx, value)
+	if err != nil {
+		return nil, fmt.Errorf("stage %s: %w", s.name, err)
+	}
+	return result, nil
+}
+
+// Pipeline converts In to Out by calling its stages one by one,
+// pipelines are immutable, so they can be shared and extended safely
+type Pipeline[In, Out any] struct {
+	stages []stage
+}
+
+func NewPipeline[T any]() Pipeline[T, T] {
+	return Pipeline[T, T]{}
+}
+
+// Then appends stage converting B to C, it can't be a method
+// because methods can't have own type parameters
+func Then[A, B, C any](p Pipeline[A, B], name string, fn func(context.Context, B) (C, error), middleware ...Middleware) Pipeline[A, C] {
+	run := func(ctx context.Context, value any) (any, error) {
+		typed, _ := value.(B) // nil interface is a zero value of B
+		return fn(ctx, typed)
+	}
+	for _, wrap := range middleware {
+		run = wrap(name, run)
+	}
+
+	stages := make([]stage, len(p.stages), len(p.stages)+1)
+	copy(stages, p.stages)
+	return Pipeline[A, C]{stages: append(stages, stage{name: name, run: run})}
+}
+
+func ThenFunc[A, B, C any](p Pipeline[A, B], name string, fn func(B) C, middleware ...Middleware) Pipeline[A, C] {
+	return Then(p, name, func(_ context.Context, value B) (C, error) {
+		return fn(value), nil
+	}, middleware...)
+}
+
+// Run stops on the first failed stage, next stages are not called
+func (p Pipeline[In, Out]) Run(ctx context.Context, value In) (Out, error) {
+	var current any = value
+	for _, s := range p.stages {
+		if err := ctx.Err(); err != nil {
+			var zero Out
+			return zero, err
+		}
+
+		var err error
+		if current, err = s.call(ctx, current); err != nil {
+			var zero Out
+			return zero, err
+		}
+	}
+
+	result, _ := current.(Out)
+	return result, nil
+}
+
+// Stream runs every stage in its own goroutine connected with channels
+// of the buffer size, the order of values is preserved. The first error
+// stops all stages and is sent to the error channel, both channels are
+// closed when processing is over.
+func (p Pipeline[In, Out]) Stream(ctx context.Context, input <-chan In, buffer int) (<-chan Out, <-chan error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	output := make(chan Out, buffer)
+	errs := make(chan error, 1)
+
+	var wg sync.WaitGroup
+	current := make(chan any, buffer)
+	wg.Add(1)
+	go func(first chan<- any) {
+		defer wg.Done()
+		feed(ctx, input, first)
+	}(current)
+
+	for _, s := range p.stages {
+		next := make(chan any, buffer)
+		wg.Add(1)
+		go func(in <-chan any) {
+			defer wg.Done()
+			defer close(next)
+			for {
+				value, ok := receive(ctx, in)
+				if !ok {
+					return
+				}
+
+				result, err := s.call(ctx, value)
+				if err != nil {
+					cancel(err)
+					return
+				}
+				if !send(ctx, next, result) {
+					return
+				}
+			}
+		}(current)
+		current = next
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(output)
+		for {
+			value, ok := receive(ctx, current)
+			if !ok {
+				break
+			}
+
+			result, _ := value.(Out)
+			if !send(ctx, output, result) {
+				break
+			}
+		}
+
+		wg.Wait()
+		if err := context.Cause(ctx); err != nil {
+			errs <- err
+		}
+		cancel(nil)
+	}()
+
+	return output, errs
+}
+
+func feed[T any](ctx context.Context, input <-chan T, output chan<- any) {
+	defer close(output)
+	for {
+		value, ok := receive(ctx, input)
+		if !ok || !send[any](ctx, output, value) {
+			return
+		}
+	}
+}
+
+func receive[T any](ctx context.Context, input <-chan T) (T, bool) {
+	select {
+	case value, ok := <-input:
+		return value, ok
+	case <-ctx.Done():
+		var zero T
+		return zero, false
+	}
+}
+
+func send[T any](ctx context.Context, output chan<- T, value T) bool {
+	select {
+	case output <- value:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func Timing(observe func(name string, elapsed time.Duration)) Middleware {
+	return func(name string, next StageFunc) StageFunc {
+		return func(ctx context.Context, value any) (any, error) {
+			start := time.Now()
+			defer func() { observe(name, time.Since(start)) }()
+			return next(ctx, value)
+		}
+	}
+}
+
+func Logging(logger *log.Logger) Middleware {
+	return func(name string, next StageFunc) StageFunc {
+		return func(ctx context.Context, value any) (any, error) {
+			result, err := next(ctx, value)
+			if err != nil {
+				logger.Printf("stage %s: %v -> error: %v", name, value, err)
+			} else {
+				logger.Printf("stage %s: %v -> %v", name, value, result)
+			}
+			return result, err
+		}
+	}
+}
+
+// Retry calls stage up to attempts times while it fails, waiting delay
+// between attempts, so the stage must be idempotent
+func Retry(attempts int, delay time.Duration) Middleware {
+	return func(name string, next StageFunc) StageFunc {
+		return func(ctx context.Context, value any) (any, error) {
+			result, err := next(ctx, value)
+			for attempt := 1; attempt < attempts && err != nil; attempt++ {
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+				result, err = next(ctx, value)
+			}
+			return result, err
+		}
+	}
+}
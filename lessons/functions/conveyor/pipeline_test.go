@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v -race .
+
+func parse(_ context.Context, value string) (int, error) {
+	return strconv.Atoi(value)
+}
+
+func newCalculation() Pipeline[string, string] {
+	numbers := Then(NewPipeline[string](), "parse", parse)
+	numbers = ThenFunc(ThenFunc(ThenFunc(numbers, "sqr", sqr), "neg", neg), "inc", inc)
+	return ThenFunc(numbers, "format", strconv.Itoa)
+}
+
+func TestPipelineRun(t *testing.T) {
+	pipeline := newCalculation()
+
+	result, err := pipeline.Run(context.Background(), "5")
+	require.NoError(t, err)
+	assert.Equal(t, "-24", result)
+	assert.Equal(t, strconv.Itoa(pipe(5, sqr, neg, inc)), result)
+
+	_, err = pipeline.Run(context.Background(), "five")
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+	assert.ErrorContains(t, err, "stage parse: ")
+
+	identity, err := NewPipeline[int]().Run(context.Background(), 5)
+	require.NoError(t, err)
+	assert.Equal(t, 5, identity)
+}
+
+func TestPipelineShortCircuit(t *testing.T) {
+	errNegative := errors.New("negative number")
+
+	var calls int
+	pipeline := Then(NewPipeline[int](), "check", func(_ context.Context, number int) (int, error) {
+		if number < 0 {
+			return 0, errNegative
+		}
+		return number, nil
+	})
+	pipeline = ThenFunc(pipeline, "count", func(number int) int {
+		calls++
+		return number
+	})
+
+	_, err := pipeline.Run(context.Background(), -1)
+	assert.ErrorIs(t, err, errNegative)
+	assert.Zero(t, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = pipeline.Run(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, calls)
+}
+
+func TestPipelineImmutable(t *testing.T) {
+	base := ThenFunc(NewPipeline[int](), "sqr", sqr)
+	negative := ThenFunc(base, "neg", neg)
+	incremented := ThenFunc(base, "inc", inc)
+
+	result, _ := negative.Run(context.Background(), 3)
+	assert.Equal(t, -9, result)
+	result, _ = incremented.Run(context.Background(), 3)
+	assert.Equal(t, 10, result)
+}
+
+func TestMiddleware(t *testing.T) {
+	var timings []string
+	timing := Timing(func(name string, _ time.Duration) {
+		timings = append(timings, name)
+	})
+
+	var buffer bytes.Buffer
+	logging := Logging(log.New(&buffer, "", 0))
+
+	errUnavailable := errors.New("service unavailable")
+	var attempts int
+	flaky := func(_ context.Context, number int) (int, error) {
+		if attempts++; attempts < 3 {
+			return 0, errUnavailable
+		}
+		return number, nil
+	}
+
+	pipeline := ThenFunc(NewPipeline[int](), "sqr", sqr, timing, logging)
+	pipeline = Then(pipeline, "flaky", flaky, logging, Retry(3, time.Millisecond))
+
+	result, err := pipeline.Run(context.Background(), 4)
+	require.NoError(t, err)
+	assert.Equal(t, 16, result)
+	assert.Equal(t, []string{"sqr"}, timings)
+	assert.Equal(t, 3, attempts)
+
+	expected := "stage sqr: 4 -> 16\n" +
+		"stage flaky: 16 -> error: service unavailable\n" +
+		"stage flaky: 16 -> error: service unavailable\n" +
+		"stage flaky: 16 -> 16\n"
+	assert.Equal(t, expected, buffer.String())
+
+	attempts = 0
+	_, err = Then(NewPipeline[int](), "flaky", flaky, Retry(2, time.Millisecond)).Run(context.Background(), 1)
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, 2, attempts)
+}
+
+func generate[T any](values ...T) <-chan T {
+	input := make(chan T)
+	go func() {
+		defer close(input)
+		for _, value := range values {
+			input <- value
+		}
+	}()
+	return input
+}
+
+func TestPipelineStream(t *testing.T) {
+	output, errs := newCalculation().Stream(context.Background(), generate("1", "2", "3", "4", "5"), 1)
+
+	var results []string
+	for result := range output {
+		results = append(results, result)
+	}
+	assert.Equal(t, []string{"0", "-3", "-8", "-15", "-24"}, results)
+	assert.NoError(t, <-errs)
+}
+
+func TestPipelineStreamError(t *testing.T) {
+	values := make([]string, 1000)
+	for i := range values {
+		values[i] = strconv.Itoa(i)
+	}
+	values[10] = "ten"
+
+	output, errs := newCalculation().Stream(context.Background(), generate(values...), 2)
+
+	var count int
+	for range output {
+		count++
+	}
+	assert.Less(t, count, len(values))
+	assert.ErrorIs(t, <-errs, strconv.ErrSyntax)
+}
+
+func TestPipelineStreamCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// input is never closed, the pipeline must stop on cancellation
+	input := make(chan int)
+	output, errs := ThenFunc(NewPipeline[int](), "sqr", sqr).Stream(ctx, input, 0)
+
+	input <- 3
+	assert.Equal(t, 9, <-output)
+	cancel()
+
+	for range output {
+	}
+	assert.ErrorIs(t, <-errs, context.Canceled)
+}
@@ -0,0 +1,52 @@
+package main
+
+func Curry2[A, B, R any](fn func(A, B) R) func(A) func(B) R {
+	return func(a A) func(B) R {
+		return func(b B) R {
+			return fn(a, b)
+		}
+	}
+}
+
+func Curry3[A, B, C, R any](fn func(A, B, C) R) func(A) func(B) func(C) R {
+	return func(a A) func(B) func(C) R {
+		return Curry2(func(b B, c C) R {
+			return fn(a, b, c)
+		})
+	}
+}
+
+func Uncurry2[A, B, R any](fn func(A) func(B) R) func(A, B) R {
+	return func(a A, b B) R {
+		return fn(a)(b)
+	}
+}
+
+// Partial1 fixes the first argument of fn
+func Partial1[A, B, R any](fn func(A, B) R, a A) func(B) R {
+	return func(b B) R {
+		return fn(a, b)
+	}
+}
+
+// Partial2 fixes the first two arguments of fn
+func Partial2[A, B, C, R any](fn func(A, B, C) R, a A, b B) func(C) R {
+	return func(c C) R {
+		return fn(a, b, c)
+	}
+}
+
+// Flip swaps arguments, so Partial1 is able to fix the second one
+func Flip[A, B, R any](fn func(A, B) R) func(B, A) R {
+	return func(b B, a A) R {
+		return fn(a, b)
+	}
+}
+
+// Compose calls first and then second like compose from the composition
+// lesson, so Compose(f, g)(x) is g(f(x))
+func Compose[A, B, C any](first func(A) B, second func(B) C) func(A) C {
+	return func(a A) C {
+		return second(first(a))
+	}
+}
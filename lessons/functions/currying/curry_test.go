@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v .
+
+func mul(x, y int) int {
+	return x * y
+}
+
+func TestCurry(t *testing.T) {
+	curried := Curry2(mul)
+	assert.Equal(t, multiply(10)(15), curried(10)(15))
+
+	mult10 := curried(10)
+	assert.Equal(t, 50, mult10(5))
+	assert.Equal(t, 150, Uncurry2(multiply)(10, 15))
+
+	format := Curry3(func(level string, code int, message string) string {
+		return fmt.Sprintf("[%s] %d: %s", level, code, message)
+	})
+	notFound := format("error")(404)
+	assert.Equal(t, "[error] 404: page not found", notFound("page not found"))
+	assert.Equal(t, "[info] 200: ok", format("info")(200)("ok"))
+}
+
+func TestPartial(t *testing.T) {
+	hasPrefix := Flip(strings.HasPrefix)
+	isCommand := Partial1(hasPrefix, "/")
+	assert.True(t, isCommand("/start"))
+	assert.False(t, isCommand("hello"))
+
+	replaceSpaces := Partial2(strings.ReplaceAll, "a b c", " ")
+	assert.Equal(t, "a_b_c", replaceSpaces("_"))
+
+	assert.Equal(t, "ab", Flip(func(a, b string) string { return a + b })("b", "a"))
+}
+
+func TestCompose(t *testing.T) {
+	double := Partial1(mul, 2)
+	format := Compose(double, strconv.Itoa)
+	assert.Equal(t, "42", format(21))
+
+	parse := func(value string) int {
+		number, _ := strconv.Atoi(value)
+		return number
+	}
+	quadruple := Compose(Compose(parse, double), Compose(double, strconv.Itoa))
+	assert.Equal(t, "40", quadruple("10"))
+}
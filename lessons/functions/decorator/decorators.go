@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+type Func[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+type Decorator[In, Out any] func(Func[In, Out]) Func[In, Out]
+
+// Chain applies decorators so that the first one is the outermost,
+// Chain(fn, a, b) is equal to a(b(fn))
+func Chain[In, Out any](fn Func[In, Out], decorators ...Decorator[In, Out]) Func[In, Out] {
+	for idx := len(decorators) - 1; idx >= 0; idx-- {
+		fn = decorators[idx](fn)
+	}
+	return fn
+}
+
+// Clock allows to replace time in tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+var RealClock Clock = realClock{}
+
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	select {
+	case <-clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func Logging[In, Out any](logger *log.Logger, name string) Decorator[In, Out] {
+	return func(fn Func[In, Out]) Func[In, Out] {
+		return func(ctx context.Context, in In) (Out, error) {
+			out, err := fn(ctx, in)
+			if err != nil {
+				logger.Printf("%s(%v) failed: %v", name, in, err)
+			} else {
+				logger.Printf("%s(%v) = %v", name, in, out)
+			}
+			return out, err
+		}
+	}
+}
+
+// Backoff returns delay before the attempt, attempts are counted from 1
+type Backoff func(attempt int) time.Duration
+
+// ExponentialBackoff doubles base delay for every attempt up to maxDelay, jitter
+// from [0, 1] randomly decreases delay by up to this fraction, random must
+// return numbers from [0, 1), rand.Float64 is used if random is nil
+func ExponentialBackoff(base, maxDelay time.Duration, jitter float64, random func() float64) Backoff {
+	if random == nil {
+		random = rand.Float64
+	}
+
+	return func(attempt int) time.Duration {
+		delay := float64(base) * math.Pow(2, float64(attempt-1))
+		delay = math.Min(delay, float64(maxDelay))
+		delay -= delay * jitter * random()
+		return time.Duration(delay)
+	}
+}
+
+// Retry calls fn up to attempts times while it fails,
+// it stops waiting when ctx is done
+func Retry[In, Out any](attempts int, backoff Backoff, clock Clock) Decorator[In, Out] {
+	return func(fn Func[In, Out]) Func[In, Out] {
+		return func(ctx context.Context, in In) (Out, error) {
+			out, err := fn(ctx, in)
+			for attempt := 1; attempt < attempts && err != nil; attempt++ {
+				if sleepErr := sleep(ctx, clock, backoff(attempt)); sleepErr != nil {
+					return out, errors.Join(err, sleepErr)
+				}
+				out, err = fn(ctx, in)
+			}
+			return out, err
+		}
+	}
+}
+
+// Timeout returns context.DeadlineExceeded after timeout even if fn
+// ignores ctx, in this case fn keeps working in background. The timer
+// of the clock cancels ctx of fn with context.DeadlineExceeded cause.
+func Timeout[In, Out any](timeout time.Duration, clock Clock) Decorator[In, Out] {
+	type result struct {
+		out Out
+		err error
+	}
+
+	return func(fn Func[In, Out]) Func[In, Out] {
+		return func(ctx context.Context, in In) (Out, error) {
+			ctx, cancel := context.WithCancelCause(ctx)
+			defer cancel(nil)
+
+			timer := clock.After(timeout)
+			done := make(chan result, 1)
+			go func() {
+				out, err := fn(ctx, in)
+				done <- result{out: out, err: err}
+			}()
+
+			var zero Out
+			select {
+			case res := <-done:
+				return res.out, res.err
+			case <-timer:
+				cancel(context.DeadlineExceeded)
+				return zero, context.DeadlineExceeded
+			case <-ctx.Done():
+				return zero, context.Cause(ctx)
+			}
+		}
+	}
+}
+
+type tokenBucket struct {
+	mutex    sync.Mutex
+	clock    Clock
+	interval time.Duration
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+// reserve takes a token in advance, tokens may become negative,
+// it returns how long to wait until the token is available
+func (b *tokenBucket) reserve() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.clock.Now()
+	b.tokens = math.Min(b.burst, b.tokens+float64(now.Sub(b.last))/float64(b.interval))
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens * float64(b.interval))
+}
+
+func (b *tokenBucket) cancel() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.tokens++
+}
+
+// RateLimit allows burst calls at once and then one call per interval,
+// calls over the limit wait for a token. Functions decorated with the same
+// decorator share the limit.
+func RateLimit[In, Out any](interval time.Duration, burst int, clock Clock) Decorator[In, Out] {
+	bucket := &tokenBucket{
+		clock:    clock,
+		interval: interval,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     clock.Now(),
+	}
+
+	return func(fn Func[In, Out]) Func[In, Out] {
+		return func(ctx context.Context, in In) (Out, error) {
+			if err := sleep(ctx, clock, bucket.reserve()); err != nil {
+				bucket.cancel()
+				var zero Out
+				return zero, err
+			}
+			return fn(ctx, in)
+		}
+	}
+}
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+var errCallPanicked = errors.New("call panicked")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+type circuitBreaker struct {
+	mutex     sync.Mutex
+	clock     Clock
+	threshold int
+	cooldown  time.Duration
+	state     circuitState
+	failures  int
+	openedAt  time.Time
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if b.clock.Now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = circuitHalfOpen // only one trial call
+		return true
+	case circuitHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+func (b *circuitBreaker) report(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if err == nil {
+		b.state, b.failures = circuitClosed, 0
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		b.state, b.openedAt = circuitOpen, b.clock.Now()
+	}
+}
+
+// CircuitBreaker fails fast with ErrCircuitOpen after threshold consecutive
+// failures, after cooldown one trial call decides whether to close circuit
+func CircuitBreaker[In, Out any](threshold int, cooldown time.Duration, clock Clock) Decorator[In, Out] {
+	breaker := &circuitBreaker{clock: clock, threshold: threshold, cooldown: cooldown}
+
+	return func(fn Func[In, Out]) Func[In, Out] {
+		return func(ctx context.Context, in In) (out Out, err error) {
+			if !breaker.allow() {
+				return out, ErrCircuitOpen
+			}
+
+			// panic is reported as failure, otherwise
+			// the trial call would leave the circuit half-open
+			err = errCallPanicked
+			defer func() {
+				breaker.report(err)
+			}()
+
+			return fn(ctx, in)
+		}
+	}
+}
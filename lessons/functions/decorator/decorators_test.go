@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v -race .
+
+// fakeClock doesn't wait, After moves time forward immediately
+type fakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// manualClock passes timers to the test, they fire only when the test sends to them
+type manualClock struct {
+	fakeClock
+	timers chan chan<- time.Time
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{fakeClock: *newFakeClock(), timers: make(chan chan<- time.Time, 10)}
+}
+
+func (c *manualClock) After(time.Duration) <-chan time.Time {
+	timer := make(chan time.Time, 1)
+	c.timers <- timer
+	return timer
+}
+
+var errUnavailable = errors.New("service unavailable")
+
+// flaky fails the first failures calls
+func flaky(failures int) (Func[int, int], *int) {
+	var calls int
+	return func(_ context.Context, x int) (int, error) {
+		if calls++; calls <= failures {
+			return 0, errUnavailable
+		}
+		return Add(x, x), nil
+	}, &calls
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	trace := func(name string) Decorator[int, int] {
+		return func(fn Func[int, int]) Func[int, int] {
+			return func(ctx context.Context, x int) (int, error) {
+				order = append(order, name)
+				return fn(ctx, x)
+			}
+		}
+	}
+
+	double := func(_ context.Context, x int) (int, error) { return Mul(x, 2), nil }
+	result, err := Chain(double, trace("outer"), trace("inner"))(context.Background(), 5)
+	require.NoError(t, err)
+	assert.Equal(t, 10, result)
+	assert.Equal(t, []string{"outer", "inner"}, order)
+}
+
+func TestLogging(t *testing.T) {
+	var buffer bytes.Buffer
+	fn, _ := flaky(1)
+	fn = Chain(fn, Logging[int, int](log.New(&buffer, "", 0), "double"))
+
+	_, _ = fn(context.Background(), 10)
+	_, _ = fn(context.Background(), 10)
+	assert.Equal(t, "double(10) failed: service unavailable\ndouble(10) = 20\n", buffer.String())
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(100*time.Millisecond, time.Second, 0, nil)
+	var delays []time.Duration
+	for attempt := 1; attempt <= 6; attempt++ {
+		delays = append(delays, backoff(attempt))
+	}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	assert.Equal(t, expected, delays)
+
+	withJitter := ExponentialBackoff(100*time.Millisecond, time.Second, 0.5, func() float64 { return 0.5 })
+	assert.Equal(t, 150*time.Millisecond, withJitter(2))
+}
+
+func TestRetry(t *testing.T) {
+	clock := newFakeClock()
+	backoff := ExponentialBackoff(10*time.Millisecond, time.Second, 0, nil)
+
+	fn, calls := flaky(2)
+	result, err := Chain(fn, Retry[int, int](3, backoff, clock))(context.Background(), 5)
+	require.NoError(t, err)
+	assert.Equal(t, 10, result)
+	assert.Equal(t, 3, *calls)
+	assert.Equal(t, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond}, clock.sleeps)
+
+	fn, calls = flaky(5)
+	_, err = Chain(fn, Retry[int, int](3, backoff, clock))(context.Background(), 5)
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, 3, *calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fn, calls = flaky(5)
+	_, err = Chain(fn, Retry[int, int](3, backoff, RealClock))(ctx, 5)
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestTimeout(t *testing.T) {
+	clock := newManualClock()
+	release := make(chan struct{})
+	defer close(release)
+
+	// ignores context, so only the decorator is able to stop waiting
+	stuck := func(_ context.Context, x int) (int, error) {
+		<-release
+		return x, nil
+	}
+	go func() { (<-clock.timers) <- clock.Now() }()
+	_, err := Chain(stuck, Timeout[int, int](time.Second, clock))(context.Background(), 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// context of fn is canceled with the cause
+	cause := make(chan error, 1)
+	obedient := func(ctx context.Context, x int) (int, error) {
+		<-ctx.Done()
+		cause <- context.Cause(ctx)
+		return x, ctx.Err()
+	}
+	go func() { (<-clock.timers) <- clock.Now() }()
+	_, err = Chain(obedient, Timeout[int, int](time.Second, clock))(context.Background(), 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, <-cause, context.DeadlineExceeded)
+
+	// the timer doesn't fire, so the result of fn is returned
+	fast := func(_ context.Context, x int) (int, error) { return x, nil }
+	result, err := Chain(fast, Timeout[int, int](time.Second, clock))(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result)
+}
+
+func TestRateLimit(t *testing.T) {
+	clock := newFakeClock()
+	fn, calls := flaky(0)
+	fn = Chain(fn, RateLimit[int, int](100*time.Millisecond, 2, clock))
+
+	for range 4 {
+		_, err := fn(context.Background(), 1)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 4, *calls)
+	// burst passes at once, others wait for a token
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond}, clock.sleeps)
+
+	clock.Advance(time.Second)
+	clock.sleeps = nil
+	for range 2 {
+		_, _ = fn(context.Background(), 1)
+	}
+	assert.Empty(t, clock.sleeps)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limited := Chain(fn, RateLimit[int, int](time.Hour, 1, RealClock))
+	_, _ = limited(ctx, 1)
+	_, err := limited(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := newFakeClock()
+	fn, calls := flaky(4)
+	fn = Chain(fn, CircuitBreaker[int, int](3, time.Minute, clock))
+
+	for range 3 {
+		_, err := fn(context.Background(), 1)
+		assert.ErrorIs(t, err, errUnavailable)
+	}
+
+	// the circuit is open, fn isn't called
+	_, err := fn(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 3, *calls)
+
+	// the trial call fails and opens the circuit again
+	clock.Advance(time.Minute)
+	_, err = fn(context.Background(), 1)
+	assert.ErrorIs(t, err, errUnavailable)
+	_, err = fn(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// the trial call succeeds and closes the circuit
+	clock.Advance(time.Minute)
+	for range 2 {
+		result, err := fn(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, result)
+	}
+	assert.Equal(t, 6, *calls)
+}
+
+func TestCircuitBreakerPanic(t *testing.T) {
+	clock := newFakeClock()
+	var calls int
+	fn := Chain(func(_ context.Context, in int) (int, error) {
+		calls++
+		if calls <= 2 {
+			panic("unexpected state")
+		}
+		return in, nil
+	}, CircuitBreaker[int, int](1, time.Minute, clock))
+
+	assert.Panics(t, func() { _, _ = fn(context.Background(), 1) })
+	_, err := fn(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// the panicked trial call opens the circuit again instead of keeping it half-open
+	clock.Advance(time.Minute)
+	assert.Panics(t, func() { _, _ = fn(context.Background(), 1) })
+	clock.Advance(time.Minute)
+	result, err := fn(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result)
+}
+
+func TestChainDecorators(t *testing.T) {
+	clock := newFakeClock()
+	var buffer bytes.Buffer
+
+	fn, calls := flaky(1)
+	fn = Chain(fn,
+		Logging[int, int](log.New(&buffer, "", 0), "double"),
+		Retry[int, int](3, ExponentialBackoff(time.Second, time.Minute, 0, nil), clock),
+		CircuitBreaker[int, int](5, time.Minute, clock),
+		Timeout[int, int](time.Second, newManualClock()),
+	)
+
+	result, err := fn(context.Background(), 21)
+	require.NoError(t, err)
+	assert.Equal(t, 42, result)
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, "double(21) = 42\n", buffer.String())
+}
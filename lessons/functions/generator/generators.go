@@ -0,0 +1,67 @@
+package main
+
+import (
+	"iter"
+	"math"
+)
+
+// FromFunc adapts closure generators like Generator to iter.Seq,
+// the sequence is infinite, so it must be stopped by the consumer
+func FromFunc[T any](next func() T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for yield(next()) {
+		}
+	}
+}
+
+// Count yields start, start+1, ... until int overflows
+func Count(start int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for number := start; yield(number) && number < math.MaxInt; number++ {
+		}
+	}
+}
+
+// Range yields numbers from [from, to) with step, negative step
+// makes decreasing sequence from (to, from]
+func Range(from, to, step int) iter.Seq[int] {
+	if step == 0 {
+		panic("range step must not be zero")
+	}
+
+	return func(yield func(int) bool) {
+		for number := from; (step > 0 && number < to) || (step < 0 && number > to); number += step {
+			if !yield(number) {
+				return
+			}
+
+			// the next number overflows
+			if (step > 0 && number > math.MaxInt-step) || (step < 0 && number < math.MinInt-step) {
+				return
+			}
+		}
+	}
+}
+
+// Fibonacci yields 1, 1, 2, 3, 5, ... and stops before int overflows
+func Fibonacci() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		previous, current := 0, 1
+		for yield(current) && current <= math.MaxInt-previous {
+			previous, current = current, previous+current
+		}
+	}
+}
+
+// Random yields pseudo-random numbers of the linear congruential
+// generator from lessons/functions/random_generator
+func Random(seed int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for number := mutate(seed); yield(number); number = mutate(number) {
+		}
+	}
+}
+
+func mutate(number int) int {
+	return (1664525*number + 1013904223) % 2147483647
+}
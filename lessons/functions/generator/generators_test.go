@@ -0,0 +1,101 @@
+package main
+
+import (
+	"iter"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v .
+
+func take[T any](seq iter.Seq[T], count int) []T {
+	var result []T
+	for value := range seq {
+		if len(result) == count {
+			break
+		}
+		result = append(result, value)
+	}
+	return result
+}
+
+func TestGenerators(t *testing.T) {
+	tests := map[string]struct {
+		seq    iter.Seq[int]
+		result []int
+	}{
+		"from closure": {
+			seq:    FromFunc(Generator(100)),
+			result: []int{100, 101, 102},
+		},
+		"count": {
+			seq:    Count(-1),
+			result: []int{-1, 0, 1},
+		},
+		"count until overflow": {
+			seq:    Count(math.MaxInt - 1),
+			result: []int{math.MaxInt - 1, math.MaxInt},
+		},
+		"range": {
+			seq:    Range(0, 10, 3),
+			result: []int{0, 3, 6, 9},
+		},
+		"decreasing range": {
+			seq:    Range(5, 0, -2),
+			result: []int{5, 3, 1},
+		},
+		"empty range": {
+			seq: Range(5, 0, 1),
+		},
+		"range until overflow": {
+			seq:    Range(math.MaxInt-3, math.MaxInt, 2),
+			result: []int{math.MaxInt - 3, math.MaxInt - 1},
+		},
+		"fibonacci": {
+			seq:    Fibonacci(),
+			result: []int{1, 1, 2, 3, 5, 8, 13, 21, 34, 55},
+		},
+		"random": {
+			seq:    Random(1),
+			result: []int{1015568748, 1586792639, 321897341},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.result, take(test.seq, len(test.result)+1)[:len(test.result)])
+		})
+	}
+}
+
+func TestFibonacciStopsBeforeOverflow(t *testing.T) {
+	numbers := slices.Collect(Fibonacci())
+	assert.Len(t, numbers, 92)
+	assert.Equal(t, 7540113804746346429, numbers[len(numbers)-1])
+	assert.True(t, slices.IsSorted(numbers))
+}
+
+func TestGeneratorsStop(t *testing.T) {
+	var calls int
+	generator := Generator(0)
+	seq := FromFunc(func() int {
+		calls++
+		return generator()
+	})
+
+	for number := range seq {
+		if number == 2 {
+			break
+		}
+	}
+	assert.Equal(t, 3, calls)
+
+	// the sequence is restartable, the closure keeps its state
+	assert.Equal(t, []int{3, 4}, take(seq, 2))
+	assert.Equal(t, []int{1, 1}, take(Fibonacci(), 2))
+	assert.Equal(t, take(Random(1), 3), take(Random(1), 3))
+	assert.Panics(t, func() { Range(0, 1, 0) })
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Lazy computes value on the first call of Get, concurrent calls wait for
+// the first one. If fn panics, the value stays zero and fn isn't called again.
+type Lazy[T any] struct {
+	once  sync.Once
+	fn    func() T
+	value T
+}
+
+func NewLazy[T any](fn func() T) *Lazy[T] {
+	return &Lazy[T]{fn: fn}
+}
+
+func (l *Lazy[T]) Get() T {
+	l.once.Do(func() {
+		l.value = l.fn()
+		l.fn = nil // for GC
+	})
+	return l.value
+}
+
+// LazyWithError doesn't remember errors, fn is called again
+// on the next Get until it succeeds
+type LazyWithError[T any] struct {
+	done  atomic.Bool
+	mutex sync.Mutex
+	fn    func() (T, error)
+	value T
+}
+
+func NewLazyWithError[T any](fn func() (T, error)) *LazyWithError[T] {
+	return &LazyWithError[T]{fn: fn}
+}
+
+func (l *LazyWithError[T]) Get() (T, error) {
+	if l.done.Load() {
+		return l.value, nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.done.Load() {
+		return l.value, nil
+	}
+
+	value, err := l.fn()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	l.value, l.fn = value, nil
+	l.done.Store(true)
+	return value, nil
+}
+
+type lazyState[T any] struct {
+	once  sync.Once
+	value T
+}
+
+// ResettableLazy computes value again on the first Get after Reset,
+// calls of Get which started before Reset return the previous value
+type ResettableLazy[T any] struct {
+	fn    func() T
+	state atomic.Pointer[lazyState[T]]
+}
+
+func NewResettableLazy[T any](fn func() T) *ResettableLazy[T] {
+	l := &ResettableLazy[T]{fn: fn}
+	l.state.Store(&lazyState[T]{})
+	return l
+}
+
+func (l *ResettableLazy[T]) Get() T {
+	state := l.state.Load()
+	state.once.Do(func() {
+		state.value = l.fn()
+	})
+	return state.value
+}
+
+func (l *ResettableLazy[T]) Reset() {
+	l.state.Store(&lazyState[T]{})
+}
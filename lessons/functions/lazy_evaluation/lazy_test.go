@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v -race .
+
+func getConcurrently[T any](goroutines int, get func() T) []T {
+	results := make([]T, goroutines)
+	wg := sync.WaitGroup{}
+	wg.Add(goroutines)
+	for idx := range goroutines {
+		go func() {
+			defer wg.Done()
+			results[idx] = get()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func TestMake(t *testing.T) {
+	var calls atomic.Int32
+	data := Make(func() map[string]string {
+		calls.Add(1)
+		return map[string]string{"key": "value"}
+	})
+
+	results := getConcurrently(10, data)
+	for _, result := range results {
+		assert.Equal(t, "value", result["key"])
+	}
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestLazy(t *testing.T) {
+	var calls atomic.Int32
+	lazy := NewLazy(func() int {
+		calls.Add(1)
+		return 42
+	})
+	assert.Zero(t, calls.Load())
+
+	results := getConcurrently(10, lazy.Get)
+	assert.Equal(t, []int{42, 42, 42, 42, 42, 42, 42, 42, 42, 42}, results)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestLazyWithError(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+
+	var calls int
+	lazy := NewLazyWithError(func() (string, error) {
+		if calls++; calls < 3 {
+			return "", errUnavailable
+		}
+		return "connection", nil
+	})
+
+	for range 2 {
+		_, err := lazy.Get()
+		assert.ErrorIs(t, err, errUnavailable)
+	}
+
+	for range 2 {
+		value, err := lazy.Get()
+		require.NoError(t, err)
+		assert.Equal(t, "connection", value)
+	}
+	assert.Equal(t, 3, calls)
+
+	var concurrentCalls atomic.Int32
+	concurrent := NewLazyWithError(func() (int, error) {
+		return int(concurrentCalls.Add(1)), nil
+	})
+	for _, result := range getConcurrently(10, func() int { value, _ := concurrent.Get(); return value }) {
+		assert.Equal(t, 1, result)
+	}
+}
+
+func TestResettableLazy(t *testing.T) {
+	var calls atomic.Int32
+	lazy := NewResettableLazy(func() int32 {
+		return calls.Add(1)
+	})
+
+	for _, result := range getConcurrently(10, lazy.Get) {
+		assert.Equal(t, int32(1), result)
+	}
+
+	lazy.Reset()
+	assert.Equal(t, int32(1), calls.Load())
+	for _, result := range getConcurrently(10, lazy.Get) {
+		assert.Equal(t, int32(2), result)
+	}
+}
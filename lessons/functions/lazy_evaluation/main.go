@@ -2,22 +2,15 @@ package main
 
 import (
 	"fmt"
+	"sync"
 )
 
 type LazyMap func() map[string]string
 
+// Make is safe for concurrent calls, sync.OnceValue drops ctr
+// after the first call, so it can be collected by GC
 func Make(ctr func() map[string]string) LazyMap {
-	var initialized bool
-	var data map[string]string
-	return func() map[string]string {
-		if !initialized {
-			data = ctr()
-			initialized = true
-			ctr = nil // for GC
-		}
-
-		return data
-	}
+	return sync.OnceValue(ctr)
 }
 
 func main() {
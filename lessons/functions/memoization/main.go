@@ -9,6 +9,10 @@ func Fibonacci(number int) int {
 }
 
 func FibonacciWithMemoization(number int) int {
+	if number <= 2 {
+		return 1
+	}
+
 	cache := make([]int, number+1)
 	var impl func(number int) int
 	impl = func(n int) int {
@@ -16,7 +20,7 @@ func FibonacciWithMemoization(number int) int {
 			return cache[n]
 		}
 
-		if number <= 2 {
+		if n <= 2 {
 			return 1
 		} else {
 			cache[n] = impl(n-1) + impl(n-2)
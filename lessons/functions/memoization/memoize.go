@@ -0,0 +1,216 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type Stats struct {
+	Hits         uint64
+	Misses       uint64
+	Deduplicated uint64 // calls waited for result of the same key in flight
+	Evictions    uint64 // entries removed by capacity or expired by TTL
+}
+
+type Option func(*options)
+
+type options struct {
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// WithCapacity limits number of cached results, the least recently
+// used result is evicted when the limit is exceeded
+func WithCapacity(capacity int) Option {
+	return func(o *options) {
+		o.capacity = capacity
+	}
+}
+
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithClock replaces time.Now, it is useful for tests
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+	expiry  *list.Element // element of the expiry queue
+}
+
+type call[V any] struct {
+	done     chan struct{}
+	value    V
+	panicked bool
+	recovery any
+}
+
+type Memoized[K comparable, V any] struct {
+	fn      func(K) V
+	options options
+
+	mutex    sync.Mutex
+	entries  map[K]*list.Element
+	order    *list.List // front is the most recently used
+	expiry   *list.List // entries sorted by expiration time, TTL is the same for all
+	inFlight map[K]*call[V]
+	stats    Stats
+}
+
+// Memoize caches results of fn, concurrent calls for the same key
+// wait for the first one instead of calling fn again
+func Memoize[K comparable, V any](fn func(K) V, opts ...Option) *Memoized[K, V] {
+	m := &Memoized[K, V]{
+		fn:       fn,
+		options:  options{now: time.Now},
+		entries:  make(map[K]*list.Element),
+		order:    list.New(),
+		expiry:   list.New(),
+		inFlight: make(map[K]*call[V]),
+	}
+	for _, opt := range opts {
+		opt(&m.options)
+	}
+	return m
+}
+
+func (m *Memoized[K, V]) Call(key K) V {
+	m.mutex.Lock()
+	if value, ok := m.lookup(key); ok {
+		m.stats.Hits++
+		m.mutex.Unlock()
+		return value
+	}
+
+	if c, ok := m.inFlight[key]; ok {
+		m.stats.Deduplicated++
+		m.mutex.Unlock()
+		return c.wait()
+	}
+
+	c := &call[V]{done: make(chan struct{})}
+	m.inFlight[key] = c
+	m.stats.Misses++
+	m.mutex.Unlock()
+
+	m.run(key, c)
+	return c.wait()
+}
+
+func (m *Memoized[K, V]) lookup(key K) (V, bool) {
+	element, ok := m.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	cached := element.Value.(*entry[K, V])
+	if m.options.ttl > 0 && !m.options.now().Before(cached.expires) {
+		m.remove(element)
+		var zero V
+		return zero, false
+	}
+
+	m.order.MoveToFront(element)
+	return cached.value, true
+}
+
+// run calls fn outside of the lock, so fn may call other keys recursively,
+// a panic of fn is not cached and is repeated for all waiting calls
+func (m *Memoized[K, V]) run(key K, c *call[V]) {
+	defer func() {
+		if recovery := recover(); recovery != nil {
+			c.panicked, c.recovery = true, recovery
+		}
+
+		m.mutex.Lock()
+		delete(m.inFlight, key)
+		if !c.panicked {
+			m.store(key, c.value)
+		}
+		m.mutex.Unlock()
+		close(c.done)
+	}()
+
+	c.value = m.fn(key)
+}
+
+// store removes expired entries as well, otherwise keys which
+// aren't requested again would stay in the cache without capacity
+func (m *Memoized[K, V]) store(key K, value V) {
+	cached := &entry[K, V]{key: key, value: value}
+	if m.options.ttl > 0 {
+		now := m.options.now()
+		m.removeExpired(now)
+		cached.expires = now.Add(m.options.ttl)
+		cached.expiry = m.expiry.PushBack(cached)
+	}
+	m.entries[key] = m.order.PushFront(cached)
+
+	if m.options.capacity > 0 && m.order.Len() > m.options.capacity {
+		m.remove(m.order.Back())
+	}
+}
+
+func (m *Memoized[K, V]) removeExpired(now time.Time) {
+	for front := m.expiry.Front(); front != nil; front = m.expiry.Front() {
+		cached := front.Value.(*entry[K, V])
+		if now.Before(cached.expires) {
+			return
+		}
+		m.remove(m.entries[cached.key])
+	}
+}
+
+func (m *Memoized[K, V]) remove(element *list.Element) {
+	cached := element.Value.(*entry[K, V])
+	m.order.Remove(element)
+	if cached.expiry != nil {
+		m.expiry.Remove(cached.expiry)
+	}
+	delete(m.entries, cached.key)
+	m.stats.Evictions++
+}
+
+func (c *call[V]) wait() V {
+	<-c.done
+	if c.panicked {
+		panic(c.recovery)
+	}
+	return c.value
+}
+
+func (m *Memoized[K, V]) Stats() Stats {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.stats
+}
+
+func (m *Memoized[K, V]) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.order.Len()
+}
+
+func FibonacciWithMemoize(number int) int {
+	var fibonacci *Memoized[int, int]
+	fibonacci = Memoize(func(n int) int {
+		if n <= 2 {
+			return 1
+		}
+		return fibonacci.Call(n-1) + fibonacci.Call(n-2)
+	})
+
+	return fibonacci.Call(number)
+}
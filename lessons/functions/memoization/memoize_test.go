@@ -0,0 +1,157 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v -race -bench=. .
+
+func TestFibonacci(t *testing.T) {
+	for number := -1; number <= 30; number++ {
+		expected := Fibonacci(number)
+		assert.Equal(t, expected, FibonacciWithMemoization(number), number)
+		assert.Equal(t, expected, FibonacciWithMemoize(number), number)
+	}
+
+	assert.Equal(t, 12586269025, FibonacciWithMemoize(50))
+}
+
+func TestMemoize(t *testing.T) {
+	var calls int
+	memoized := Memoize(func(number int) string {
+		calls++
+		return strconv.Itoa(number)
+	})
+
+	assert.Equal(t, "1", memoized.Call(1))
+	assert.Equal(t, "2", memoized.Call(2))
+	assert.Equal(t, "1", memoized.Call(1))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, Stats{Hits: 1, Misses: 2}, memoized.Stats())
+}
+
+func TestMemoizeCapacity(t *testing.T) {
+	var calls int
+	memoized := Memoize(func(number int) int {
+		calls++
+		return number * number
+	}, WithCapacity(2))
+
+	memoized.Call(1)
+	memoized.Call(2)
+	memoized.Call(1) // 2 becomes the least recently used
+	memoized.Call(3) // evicts 2
+	assert.Equal(t, 2, memoized.Len())
+	assert.Equal(t, 3, calls)
+
+	memoized.Call(1)
+	assert.Equal(t, 3, calls)
+	memoized.Call(2)
+	assert.Equal(t, 4, calls)
+
+	assert.Equal(t, Stats{Hits: 2, Misses: 4, Evictions: 2}, memoized.Stats())
+}
+
+func TestMemoizeTTL(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	var calls int
+	memoized := Memoize(func(key string) int {
+		calls++
+		return calls
+	}, WithTTL(time.Minute), WithClock(clock))
+
+	assert.Equal(t, 1, memoized.Call("key"))
+	now = now.Add(59 * time.Second)
+	assert.Equal(t, 1, memoized.Call("key"))
+	now = now.Add(time.Second)
+	assert.Equal(t, 2, memoized.Call("key"))
+	assert.Equal(t, Stats{Hits: 1, Misses: 2, Evictions: 1}, memoized.Stats())
+
+	// expired entries are removed without lookups of their keys
+	memoized.Call("other")
+	now = now.Add(time.Minute)
+	memoized.Call("new")
+	assert.Equal(t, 1, memoized.Len())
+	assert.Equal(t, uint64(3), memoized.Stats().Evictions)
+}
+
+func TestMemoizeDeduplication(t *testing.T) {
+	const goroutines = 10
+
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	memoized := Memoize(func(key string) string {
+		calls.Add(1)
+		close(started)
+		<-release
+		return key + "!"
+	})
+
+	results := make([]string, goroutines)
+	wg := sync.WaitGroup{}
+	wg.Add(goroutines)
+	for idx := range goroutines {
+		go func() {
+			defer wg.Done()
+			results[idx] = memoized.Call("key")
+		}()
+		if idx == 0 {
+			<-started
+		}
+	}
+
+	for memoized.Stats().Deduplicated != goroutines-1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, result := range results {
+		assert.Equal(t, "key!", result)
+	}
+	assert.Equal(t, Stats{Misses: 1, Deduplicated: goroutines - 1}, memoized.Stats())
+}
+
+func TestMemoizePanic(t *testing.T) {
+	var calls int
+	memoized := Memoize(func(number int) int {
+		if calls++; calls == 1 {
+			panic("temporary failure")
+		}
+		return number
+	})
+
+	assert.PanicsWithValue(t, "temporary failure", func() { memoized.Call(1) })
+	assert.Equal(t, 1, memoized.Call(1))
+	assert.Equal(t, 2, calls)
+}
+
+var result int
+
+func BenchmarkFibonacci(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		result = Fibonacci(30)
+	}
+}
+
+func BenchmarkFibonacciWithMemoization(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		result = FibonacciWithMemoization(30)
+	}
+}
+
+func BenchmarkFibonacciWithMemoize(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		result = FibonacciWithMemoize(30)
+	}
+}
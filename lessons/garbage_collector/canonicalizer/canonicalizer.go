@@ -0,0 +1,81 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"unique"
+	"weak"
+)
+
+// canonical identifies the entry, the value for the key
+// may be replaced after the previous one is collected
+type canonical[K comparable, V any] struct {
+	key     unique.Handle[K]
+	pointer weak.Pointer[V]
+}
+
+// Canonicalizer returns the same value for equal keys while the value
+// is used by somebody, it doesn't keep values alive by itself
+type Canonicalizer[K comparable, V any] struct {
+	mutex  sync.Mutex
+	data   map[unique.Handle[K]]weak.Pointer[V]
+	create func(key K) *V
+}
+
+// NewCanonicalizer uses create to build the value when there
+// is no alive value for the key, create must not return nil
+func NewCanonicalizer[K comparable, V any](create func(key K) *V) *Canonicalizer[K, V] {
+	return &Canonicalizer[K, V]{
+		data:   make(map[unique.Handle[K]]weak.Pointer[V]),
+		create: create,
+	}
+}
+
+// Get returns the canonical value for the key, keys are interned,
+// so large keys are stored once and compared by pointer
+func (c *Canonicalizer[K, V]) Get(key K) *V {
+	handle := unique.Make(key)
+	if value := c.load(handle); value != nil {
+		return value
+	}
+
+	// the value is created without lock, so concurrent
+	// calls may create several values, but only one is stored
+	value := c.create(key)
+	pointer := weak.Make(value)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if existing := c.data[handle].Value(); existing != nil {
+		return existing
+	}
+	c.data[handle] = pointer
+	runtime.AddCleanup(value, c.release, canonical[K, V]{key: handle, pointer: pointer})
+	return value
+}
+
+func (c *Canonicalizer[K, V]) load(handle unique.Handle[K]) *V {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.data[handle].Value()
+}
+
+// Len returns number of entries including ones
+// with collected values which cleanups haven't run yet
+func (c *Canonicalizer[K, V]) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.data)
+}
+
+// release removes the entry only if it still holds the collected value,
+// after that the interned key is released as well
+func (c *Canonicalizer[K, V]) release(canonical canonical[K, V]) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.data[canonical.key] == canonical.pointer {
+		delete(c.data, canonical.key)
+	}
+}
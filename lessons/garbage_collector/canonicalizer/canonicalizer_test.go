@@ -0,0 +1,128 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unique"
+	"weak"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v -race .
+
+// value is large enough to avoid the tiny allocator
+type value struct {
+	key  string
+	data [1024]byte
+}
+
+func newCounted(created *atomic.Int32) *Canonicalizer[string, value] {
+	return NewCanonicalizer(func(key string) *value {
+		created.Add(1)
+		return &value{key: key}
+	})
+}
+
+// waitReleased runs GC until cleanups remove all entries or timeout is exceeded
+func waitReleased[K comparable, V any](t *testing.T, canonicalizer *Canonicalizer[K, V]) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for canonicalizer.Len() != 0 {
+		require.True(t, time.Now().Before(deadline), "entries aren't released")
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCanonicalizer(t *testing.T) {
+	var created atomic.Int32
+	canonicalizer := newCounted(&created)
+
+	// keys are equal, but built separately
+	first := canonicalizer.Get(strings.Repeat("key", 100))
+	second := canonicalizer.Get(strings.Repeat("key", 100))
+	other := canonicalizer.Get("other")
+
+	assert.Same(t, first, second)
+	assert.NotSame(t, first, other)
+	assert.Equal(t, "other", other.key)
+	assert.Equal(t, int32(2), created.Load())
+	assert.Equal(t, 2, canonicalizer.Len())
+
+	runtime.GC()
+	assert.Same(t, first, canonicalizer.Get(strings.Repeat("key", 100)))
+	assert.Equal(t, int32(2), created.Load())
+	runtime.KeepAlive(first)
+	runtime.KeepAlive(second)
+	runtime.KeepAlive(other)
+}
+
+func TestCanonicalizerRelease(t *testing.T) {
+	var created atomic.Int32
+	canonicalizer := newCounted(&created)
+
+	pointer := weak.Make(canonicalizer.Get("key"))
+	waitReleased(t, canonicalizer)
+	assert.Nil(t, pointer.Value())
+
+	// the value is created again after all holders dropped it
+	assert.NotNil(t, canonicalizer.Get("key"))
+	assert.Equal(t, int32(2), created.Load())
+}
+
+func TestCanonicalizerStaleRelease(t *testing.T) {
+	var created atomic.Int32
+	canonicalizer := newCounted(&created)
+
+	previous := canonicalizer.Get("key")
+	stale := canonical[string, value]{key: unique.Make("key"), pointer: weak.Make(previous)}
+
+	// the value is collected and replaced before its cleanup runs
+	canonicalizer.data[unique.Make("key")] = weak.Pointer[value]{}
+	current := canonicalizer.Get("key")
+	require.NotSame(t, previous, current)
+
+	canonicalizer.release(stale)
+	assert.Same(t, current, canonicalizer.Get("key"))
+	assert.Equal(t, 1, canonicalizer.Len())
+	runtime.KeepAlive(previous)
+	runtime.KeepAlive(current)
+}
+
+func TestCanonicalizerConcurrent(t *testing.T) {
+	var created atomic.Int32
+	canonicalizer := newCounted(&created)
+	holder := canonicalizer.Get("key")
+
+	const workers = 8
+	values := make([]*value, workers)
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for worker := range workers {
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				values[worker] = canonicalizer.Get("key")
+				canonicalizer.Get(strings.Repeat("temporary", worker))
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, got := range values {
+		assert.Same(t, holder, got)
+	}
+	runtime.KeepAlive(holder)
+
+	values = nil
+	holder = nil
+	waitReleased(t, canonicalizer)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+type Document struct {
+	Text  string
+	Words int
+}
+
+func main() {
+	documents := NewCanonicalizer(func(text string) *Document {
+		return &Document{Text: text, Words: len(strings.Fields(text))}
+	})
+
+	first := documents.Get(strings.Repeat("lorem ipsum ", 1024))
+	second := documents.Get(strings.Repeat("lorem ipsum ", 1024))
+	fmt.Println("same document:", first == second)
+
+	first, second = nil, nil
+	runtime.GC()
+	time.Sleep(time.Second)
+
+	fmt.Println("documents:", documents.Len())
+}
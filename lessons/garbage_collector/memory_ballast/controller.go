@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	liveHeapMetric  = "/gc/heap/live:bytes"
+	totalHeapMetric = "/memory/classes/heap/objects:bytes"
+	goalHeapMetric  = "/gc/heap/goal:bytes"
+	gcPercentMetric = "/gc/gogc:percent"
+	memLimitMetric  = "/gc/gomemlimit:bytes"
+)
+
+// cgroup v2 and v1 files, limits above unlimitedMemory mean there is no limit
+var defaultCgroupFiles = []string{
+	"/sys/fs/cgroup/memory.max",
+	"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+}
+
+const unlimitedMemory = 1 << 62
+
+// Decision describes settings applied by the controller
+type Decision struct {
+	Time        time.Time
+	LiveHeap    uint64 // heap marked by the last GC cycle
+	HeapGoal    uint64 // heap size which triggers the next GC cycle
+	MemoryLimit int64
+	GCPercent   int
+	Adjusted    bool // false if the limit is unknown and settings of the runtime are kept
+}
+
+type Option func(*Controller)
+
+// WithHeadroom sets fraction of the container limit reserved for
+// memory which isn't controlled by the runtime (cgo, mmap, page cache)
+func WithHeadroom(headroom float64) Option {
+	return func(c *Controller) {
+		c.headroom = headroom
+	}
+}
+
+// WithMemoryLimit sets the limit explicitly instead of reading cgroup files
+func WithMemoryLimit(limit int64) Option {
+	return func(c *Controller) {
+		c.limit = limit
+	}
+}
+
+func WithCgroupFiles(paths ...string) Option {
+	return func(c *Controller) {
+		c.cgroupFiles = paths
+	}
+}
+
+// WithGCPercent sets bounds of GOGC, the lower bound is used when the heap
+// is close to the limit and the upper one when the heap is small
+func WithGCPercent(lower, upper int) Option {
+	return func(c *Controller) {
+		c.minPercent = lower
+		c.maxPercent = upper
+	}
+}
+
+func WithInterval(interval time.Duration) Option {
+	return func(c *Controller) {
+		c.interval = interval
+	}
+}
+
+// WithObserver sets the hook called after every decision
+func WithObserver(observer func(Decision)) Option {
+	return func(c *Controller) {
+		c.observer = observer
+	}
+}
+
+// Controller replaces memory ballast: instead of inflating the heap to make
+// GC rare, it raises GOGC while the live heap is far from the memory limit
+// and relies on the memory limit when the heap grows
+type Controller struct {
+	headroom    float64
+	limit       int64
+	cgroupFiles []string
+	minPercent  int
+	maxPercent  int
+	interval    time.Duration
+	observer    func(Decision)
+
+	// runtime functions are replaced in tests
+	readMetrics    func() runtimeMetrics
+	setGCPercent   func(int) int
+	setMemoryLimit func(int64) int64
+
+	mutex    sync.Mutex
+	decision Decision
+}
+
+type runtimeMetrics struct {
+	liveHeap    uint64
+	heapGoal    uint64
+	gcPercent   int // -1 if GC is off
+	memoryLimit int64
+}
+
+func readRuntimeMetrics() runtimeMetrics {
+	samples := []metrics.Sample{
+		{Name: liveHeapMetric},
+		{Name: totalHeapMetric},
+		{Name: goalHeapMetric},
+		{Name: gcPercentMetric},
+		{Name: memLimitMetric},
+	}
+	metrics.Read(samples)
+
+	result := runtimeMetrics{
+		liveHeap:    sampleValue(samples[0]),
+		heapGoal:    sampleValue(samples[2]),
+		gcPercent:   int(int64(sampleValue(samples[3]))),
+		memoryLimit: int64(sampleValue(samples[4])),
+	}
+	if result.liveHeap == 0 {
+		// there was no GC cycle yet
+		result.liveHeap = sampleValue(samples[1])
+	}
+	return result
+}
+
+func sampleValue(sample metrics.Sample) uint64 {
+	if sample.Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample.Value.Uint64()
+}
+
+func NewController(options ...Option) (*Controller, error) {
+	c := &Controller{
+		headroom:       0.1,
+		cgroupFiles:    defaultCgroupFiles,
+		minPercent:     25,
+		maxPercent:     800,
+		interval:       time.Second,
+		readMetrics:    readRuntimeMetrics,
+		setGCPercent:   debug.SetGCPercent,
+		setMemoryLimit: debug.SetMemoryLimit,
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	switch {
+	case c.headroom < 0 || c.headroom >= 1:
+		return nil, errors.New("headroom must be in [0, 1)")
+	case c.limit < 0:
+		return nil, errors.New("memory limit must not be negative")
+	case c.minPercent <= 0 || c.minPercent > c.maxPercent:
+		return nil, errors.New("incorrect GC percent bounds")
+	case c.interval <= 0:
+		return nil, errors.New("incorrect interval")
+	}
+
+	if c.limit == 0 {
+		c.limit = readCgroupLimit(c.cgroupFiles)
+	}
+	return c, nil
+}
+
+// readCgroupLimit returns the first limit found in files
+// or math.MaxInt64 if the process isn't limited
+func readCgroupLimit(paths []string) int64 {
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		value := strings.TrimSpace(string(data))
+		if value == "max" {
+			return math.MaxInt64
+		}
+
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit <= 0 {
+			continue
+		}
+		if limit >= unlimitedMemory {
+			return math.MaxInt64
+		}
+		return limit
+	}
+	return math.MaxInt64
+}
+
+// Adjust reads heap metrics and applies new GC settings, without the known
+// memory limit GOGC and GOMEMLIMIT set by the operator are kept as they are
+func (c *Controller) Adjust() Decision {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	current := c.readMetrics()
+	liveHeap := current.liveHeap
+	decision := Decision{Time: time.Now(), LiveHeap: liveHeap}
+	if c.limit == math.MaxInt64 {
+		decision.MemoryLimit, decision.GCPercent = current.memoryLimit, current.gcPercent
+	} else {
+		decision.MemoryLimit, decision.GCPercent = c.memoryLimit(), c.gcPercent(liveHeap)
+		decision.Adjusted = true
+		c.setMemoryLimit(decision.MemoryLimit)
+		c.setGCPercent(decision.GCPercent)
+	}
+
+	// the goal is computed by the runtime from the new settings
+	decision.HeapGoal = c.readMetrics().heapGoal
+	c.decision = decision
+
+	if c.observer != nil {
+		c.observer(decision)
+	}
+	return decision
+}
+
+func (c *Controller) memoryLimit() int64 {
+	return int64(float64(c.limit) * (1 - c.headroom))
+}
+
+// gcPercent keeps the heap goal live*(1+percent/100) under the memory limit
+func (c *Controller) gcPercent(liveHeap uint64) int {
+	if liveHeap == 0 {
+		return c.maxPercent
+	}
+
+	percent := (float64(c.memoryLimit())/float64(liveHeap) - 1) * 100
+	return int(max(float64(c.minPercent), min(float64(c.maxPercent), percent)))
+}
+
+// Decision returns the last applied decision
+func (c *Controller) Decision() Decision {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.decision
+}
+
+// Run adjusts settings every interval until the context is canceled,
+// then previous settings of the runtime are restored
+func (c *Controller) Run(ctx context.Context) {
+	c.mutex.Lock()
+	previous := c.readMetrics()
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		c.setMemoryLimit(previous.memoryLimit)
+		c.setGCPercent(previous.gcPercent)
+	}()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.Adjust()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
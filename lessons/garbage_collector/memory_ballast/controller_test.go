@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v -race .
+
+const mb = 1 << 20
+
+// fakeRuntime records settings instead of changing GC of tests
+type fakeRuntime struct {
+	liveHeap    atomic.Uint64
+	gcPercent   int
+	memoryLimit int64
+	changes     int // calls of setters
+}
+
+func newFakeRuntime(liveHeap uint64) *fakeRuntime {
+	fake := &fakeRuntime{gcPercent: 100, memoryLimit: math.MaxInt64}
+	fake.liveHeap.Store(liveHeap)
+	return fake
+}
+
+func (r *fakeRuntime) install(c *Controller) {
+	c.readMetrics = func() runtimeMetrics {
+		live := r.liveHeap.Load()
+		return runtimeMetrics{
+			liveHeap:    live,
+			heapGoal:    live * uint64(100+r.gcPercent) / 100,
+			gcPercent:   r.gcPercent,
+			memoryLimit: r.memoryLimit,
+		}
+	}
+	c.setGCPercent = func(percent int) int {
+		previous := r.gcPercent
+		r.gcPercent = percent
+		r.changes++
+		return previous
+	}
+	c.setMemoryLimit = func(limit int64) int64 {
+		previous := r.memoryLimit
+		r.changes++
+		if limit >= 0 {
+			r.memoryLimit = limit
+		}
+		return previous
+	}
+}
+
+func TestReadCgroupLimit(t *testing.T) {
+	directory := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(directory, name)
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		return path
+	}
+
+	tests := map[string]struct {
+		paths []string
+		limit int64
+	}{
+		"cgroup v2":          {paths: []string{write("v2", "536870912\n")}, limit: 512 * mb},
+		"cgroup v2 max":      {paths: []string{write("v2_max", "max\n")}, limit: math.MaxInt64},
+		"cgroup v1":          {paths: []string{write("v1", "268435456\n")}, limit: 256 * mb},
+		"cgroup v1 no limit": {paths: []string{write("v1_max", "9223372036854771712\n")}, limit: math.MaxInt64},
+		"missing files":      {paths: []string{filepath.Join(directory, "missing")}, limit: math.MaxInt64},
+		"fallback": {
+			paths: []string{filepath.Join(directory, "missing"), write("broken", "???"), write("fallback", "1048576")},
+			limit: mb,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.limit, readCgroupLimit(test.paths))
+		})
+	}
+}
+
+func TestNewControllerValidation(t *testing.T) {
+	tests := map[string]Option{
+		"negative headroom":  WithHeadroom(-0.1),
+		"full headroom":      WithHeadroom(1),
+		"zero percent":       WithGCPercent(0, 100),
+		"inverted percent":   WithGCPercent(200, 100),
+		"incorrect interval": WithInterval(0),
+		"negative limit":     WithMemoryLimit(-1),
+	}
+
+	for name, option := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewController(option)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestControllerAdjust(t *testing.T) {
+	tests := map[string]struct {
+		limit       int64
+		liveHeap    uint64
+		memoryLimit int64
+		gcPercent   int
+	}{
+		"small heap":    {limit: 1000 * mb, liveHeap: 10 * mb, memoryLimit: 900 * mb, gcPercent: 800},
+		"medium heap":   {limit: 1000 * mb, liveHeap: 300 * mb, memoryLimit: 900 * mb, gcPercent: 200},
+		"heap at limit": {limit: 1000 * mb, liveHeap: 850 * mb, memoryLimit: 900 * mb, gcPercent: 25},
+		"no gc yet":     {limit: 1000 * mb, memoryLimit: 900 * mb, gcPercent: 800},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var observed []Decision
+			controller, err := NewController(
+				WithMemoryLimit(test.limit),
+				WithObserver(func(decision Decision) { observed = append(observed, decision) }),
+			)
+			require.NoError(t, err)
+
+			fake := newFakeRuntime(test.liveHeap)
+			fake.install(controller)
+
+			decision := controller.Adjust()
+			assert.Equal(t, test.memoryLimit, decision.MemoryLimit)
+			assert.Equal(t, test.gcPercent, decision.GCPercent)
+			assert.Equal(t, test.memoryLimit, fake.memoryLimit)
+			assert.Equal(t, test.gcPercent, fake.gcPercent)
+			assert.Equal(t, test.liveHeap, decision.LiveHeap)
+			assert.True(t, decision.Adjusted)
+			assert.Equal(t, []Decision{decision}, observed)
+			assert.Equal(t, decision, controller.Decision())
+		})
+	}
+}
+
+func TestControllerCgroup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.max")
+	require.NoError(t, os.WriteFile(path, []byte("1073741824"), 0o600))
+
+	controller, err := NewController(WithCgroupFiles(path), WithHeadroom(0.5), WithGCPercent(50, 1000))
+	require.NoError(t, err)
+	newFakeRuntime(128 * mb).install(controller)
+
+	decision := controller.Adjust()
+	assert.Equal(t, int64(512*mb), decision.MemoryLimit)
+	assert.Equal(t, 300, decision.GCPercent)
+}
+
+func TestControllerWithoutLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.max")
+	require.NoError(t, os.WriteFile(path, []byte("max\n"), 0o600))
+
+	controller, err := NewController(WithCgroupFiles(path))
+	require.NoError(t, err)
+
+	// GOMEMLIMIT and GOGC=off set by the operator
+	fake := newFakeRuntime(100 * mb)
+	fake.memoryLimit, fake.gcPercent = 2048*mb, -1
+	fake.install(controller)
+
+	for range 3 {
+		decision := controller.Adjust()
+		assert.False(t, decision.Adjusted)
+		assert.Equal(t, int64(2048*mb), decision.MemoryLimit)
+		assert.Equal(t, -1, decision.GCPercent)
+	}
+
+	// settings are read from metrics, GC isn't switched on even for a moment
+	assert.Zero(t, fake.changes)
+}
+
+func TestControllerRun(t *testing.T) {
+	controller, err := NewController(WithMemoryLimit(1000*mb), WithInterval(time.Millisecond))
+	require.NoError(t, err)
+
+	fake := newFakeRuntime(100 * mb)
+	fake.gcPercent = 50
+	fake.install(controller)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		controller.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return controller.Decision().GCPercent == 800
+	}, time.Second, time.Millisecond)
+
+	fake.liveHeap.Store(600 * mb)
+	require.Eventually(t, func() bool {
+		return controller.Decision().GCPercent == 50
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+
+	// settings are restored after the controller is stopped
+	assert.Equal(t, 50, fake.gcPercent)
+	assert.Equal(t, int64(math.MaxInt64), fake.memoryLimit)
+}
+
+func TestControllerRuntime(t *testing.T) {
+	previousPercent := debug.SetGCPercent(100)
+	previousLimit := debug.SetMemoryLimit(-1)
+	defer func() {
+		debug.SetGCPercent(previousPercent)
+		debug.SetMemoryLimit(previousLimit)
+	}()
+
+	controller, err := NewController(WithMemoryLimit(4 << 30))
+	require.NoError(t, err)
+
+	runtime.GC()
+	decision := controller.Adjust()
+	assert.NotZero(t, decision.LiveHeap)
+	assert.Greater(t, decision.HeapGoal, decision.LiveHeap)
+	assert.Equal(t, decision.MemoryLimit, debug.SetMemoryLimit(-1))
+	assert.Equal(t, decision.GCPercent, debug.SetGCPercent(decision.GCPercent))
+}
+
+func TestReadRuntimeMetrics(t *testing.T) {
+	previousPercent := debug.SetGCPercent(-1)
+	previousLimit := debug.SetMemoryLimit(1 << 30)
+	defer func() {
+		debug.SetGCPercent(previousPercent)
+		debug.SetMemoryLimit(previousLimit)
+	}()
+
+	metrics := readRuntimeMetrics()
+	assert.Equal(t, -1, metrics.gcPercent)
+	assert.Equal(t, int64(1<<30), metrics.memoryLimit)
+}
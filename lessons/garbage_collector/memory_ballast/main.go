@@ -1,8 +1,34 @@
 package main
 
-func main() {
+import (
+	"context"
+	"fmt"
+)
+
+// withBallast is the old trick: the heap looks large for GC,
+// so it runs rarely, the memory isn't touched and stays virtual
+func withBallast() {
 	ballast := make([]byte, 2<<30)
 	_ = ballast
 
 	// implementation ...
 }
+
+func main() {
+	// Controller makes the ballast unnecessary: GOGC is raised while the heap
+	// is far from the memory limit of the container and is lowered near it
+	controller, err := NewController(WithObserver(func(decision Decision) {
+		fmt.Printf("live heap: %d, goal: %d, limit: %d, GOGC: %d\n",
+			decision.LiveHeap, decision.HeapGoal, decision.MemoryLimit, decision.GCPercent)
+	}))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go controller.Run(ctx)
+
+	// implementation ...
+}
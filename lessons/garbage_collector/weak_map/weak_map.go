@@ -0,0 +1,129 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"weak"
+)
+
+type weakEntry[V any] struct {
+	pointer weak.Pointer[V]
+	cleanup runtime.Cleanup
+}
+
+// evicted identifies the entry, weak pointers made from
+// different objects are not equal even if values are equal
+type evicted[K comparable, V any] struct {
+	key     K
+	pointer weak.Pointer[V]
+}
+
+// WeakMap doesn't keep values alive, entries are removed
+// after their values are collected by GC
+type WeakMap[K comparable, V any] struct {
+	mutex   sync.RWMutex
+	data    map[K]weakEntry[V]
+	onEvict func(key K)
+}
+
+func NewWeakMap[K comparable, V any]() *WeakMap[K, V] {
+	return &WeakMap[K, V]{
+		data: make(map[K]weakEntry[V]),
+	}
+}
+
+// OnEvict sets the hook called after the entry is removed because its value
+// is collected, it is called from the cleanup goroutine, so it must not block
+func (w *WeakMap[K, V]) OnEvict(hook func(key K)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.onEvict = hook
+}
+
+func (w *WeakMap[K, V]) Set(key K, value *V) {
+	pointer := weak.Make(value)
+	cleanup := runtime.AddCleanup(value, w.evict, evicted[K, V]{key: key, pointer: pointer})
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if previous, ok := w.data[key]; ok {
+		previous.cleanup.Stop()
+	}
+	w.data[key] = weakEntry[V]{pointer: pointer, cleanup: cleanup}
+}
+
+// Get returns nil if the value is collected, even if its cleanup hasn't run yet
+func (w *WeakMap[K, V]) Get(key K) *V {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	if entry, ok := w.data[key]; ok {
+		return entry.pointer.Value()
+	}
+	return nil
+}
+
+func (w *WeakMap[K, V]) Delete(key K) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if entry, ok := w.data[key]; ok {
+		entry.cleanup.Stop()
+		delete(w.data, key)
+	}
+}
+
+// Len returns number of entries with alive values
+func (w *WeakMap[K, V]) Len() int {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	var count int
+	for _, entry := range w.data {
+		if entry.pointer.Value() != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Range calls action for entries with alive values until it returns false,
+// action is called without lock, so it may use the map
+func (w *WeakMap[K, V]) Range(action func(key K, value *V) bool) {
+	w.mutex.RLock()
+	keys := make([]K, 0, len(w.data))
+	values := make([]*V, 0, len(w.data))
+	for key, entry := range w.data {
+		if value := entry.pointer.Value(); value != nil {
+			keys = append(keys, key)
+			values = append(values, value)
+		}
+	}
+	w.mutex.RUnlock()
+
+	for idx, key := range keys {
+		if !action(key, values[idx]) {
+			return
+		}
+	}
+}
+
+// evict removes the entry only if it still holds the collected value,
+// the key may be set to another value after the cleanup was scheduled
+func (w *WeakMap[K, V]) evict(evicted evicted[K, V]) {
+	w.mutex.Lock()
+	entry, ok := w.data[evicted.key]
+	if !ok || entry.pointer != evicted.pointer {
+		w.mutex.Unlock()
+		return
+	}
+
+	delete(w.data, evicted.key)
+	hook := w.onEvict
+	w.mutex.Unlock()
+
+	if hook != nil {
+		hook(evicted.key)
+	}
+}
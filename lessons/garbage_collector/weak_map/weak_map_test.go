@@ -0,0 +1,152 @@
+package main
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v -race .
+
+// payload is large enough to avoid the tiny allocator,
+// tiny objects are freed together with their neighbours
+type payload struct {
+	id   int
+	data [64]byte
+}
+
+// waitEvicted runs GC until the hook reports the key or timeout is exceeded
+func waitEvicted[K comparable](t *testing.T, evicted <-chan K, key K) {
+	t.Helper()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		runtime.GC()
+		select {
+		case got := <-evicted:
+			if got == key {
+				return
+			}
+		case <-deadline:
+			require.FailNow(t, "value is not evicted", key)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestWeakMap(t *testing.T) {
+	data := NewWeakMap[string, payload]()
+	evicted := make(chan string, 10)
+	data.OnEvict(func(key string) { evicted <- key })
+
+	alive := &payload{id: 1}
+	data.Set("alive", alive)
+	data.Set("dead", &payload{id: 2})
+	assert.Equal(t, 1, data.Get("alive").id)
+
+	waitEvicted(t, evicted, "dead")
+	assert.Nil(t, data.Get("dead"))
+	assert.Nil(t, data.Get("unknown"))
+	assert.Equal(t, alive, data.Get("alive"))
+	assert.Equal(t, 1, data.Len())
+
+	data.Delete("alive")
+	assert.Nil(t, data.Get("alive"))
+	assert.Zero(t, data.Len())
+	runtime.KeepAlive(alive)
+}
+
+func TestWeakMapResetKey(t *testing.T) {
+	data := NewWeakMap[string, payload]()
+	evicted := make(chan string, 10)
+	data.OnEvict(func(key string) { evicted <- key })
+
+	replacement := &payload{id: 2}
+	data.Set("key", &payload{id: 1})
+	data.Set("key", replacement)
+	data.Set("other", &payload{id: 3})
+
+	// cleanup of the first value doesn't remove the replacement
+	waitEvicted(t, evicted, "other")
+	runtime.GC()
+	assert.Equal(t, replacement, data.Get("key"))
+	assert.Equal(t, 1, data.Len())
+	runtime.KeepAlive(replacement)
+}
+
+func TestWeakMapStaleEviction(t *testing.T) {
+	data := NewWeakMap[string, payload]()
+	previous := &payload{id: 1}
+	data.Set("key", previous)
+	stale := evicted[string, payload]{key: "key", pointer: data.data["key"].pointer}
+
+	replacement := &payload{id: 2}
+	data.Set("key", replacement)
+
+	// cleanup of the previous value doesn't remove the replacement
+	data.evict(stale)
+	assert.Equal(t, replacement, data.Get("key"))
+
+	data.Set("key", previous)
+	data.evict(stale)
+	assert.Nil(t, data.Get("key"))
+	runtime.KeepAlive(previous)
+	runtime.KeepAlive(replacement)
+}
+
+func TestWeakMapRange(t *testing.T) {
+	data := NewWeakMap[int, payload]()
+	values := make([]*payload, 5)
+	for idx := range values {
+		values[idx] = &payload{id: idx}
+		data.Set(idx, values[idx])
+	}
+
+	seen := make(map[int]int)
+	data.Range(func(key int, value *payload) bool {
+		seen[key] = value.id
+		data.Delete(key) // the map may be used inside of action
+		return true
+	})
+	assert.Equal(t, map[int]int{0: 0, 1: 1, 2: 2, 3: 3, 4: 4}, seen)
+	assert.Zero(t, data.Len())
+
+	for idx := range values {
+		data.Set(idx, values[idx])
+	}
+	var count int
+	data.Range(func(int, *payload) bool {
+		count++
+		return count < 2
+	})
+	assert.Equal(t, 2, count)
+	runtime.KeepAlive(values)
+}
+
+func TestWeakMapConcurrent(t *testing.T) {
+	data := NewWeakMap[string, payload]()
+	data.OnEvict(func(string) {})
+
+	wg := sync.WaitGroup{}
+	wg.Add(4)
+	for worker := range 4 {
+		go func() {
+			defer wg.Done()
+			for idx := range 1000 {
+				key := strconv.Itoa(idx % 10)
+				data.Set(key, &payload{id: worker})
+				data.Get(key)
+				if idx%100 == 0 {
+					runtime.GC()
+					data.Len()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}